@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -15,16 +16,25 @@ var (
 	sendServiceCommandFunc = service.SendCommand
 	runServiceActionFunc   = service.RunAction
 	serviceStatusFunc      = service.ServiceStatus
+	showReportFunc         = showReport
 )
 
 func main() {
 	cfg := config.Get()
 	if err := route(cfg); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// exitCode returns 2 when a run completed but items failed, and 1 for any other error
+func exitCode(err error) int {
+	if errors.Is(err, errItemsFailed) {
+		return 2
+	}
+	return 1
+}
+
 func route(cfg config.Configuration) error {
 	if cfg.ServiceInstall {
 		if err := runServiceActionFunc(cfg, "install"); err != nil {
@@ -107,6 +117,10 @@ func route(cfg config.Configuration) error {
 		return nil
 	}
 
+	if cfg.ReportArg != "" {
+		return showReportFunc(cfg)
+	}
+
 	if cfg.ServiceMode {
 		return runServiceFunc(cfg)
 	}
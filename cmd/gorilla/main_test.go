@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/1dustindavis/gorilla/pkg/admin"
+	"github.com/1dustindavis/gorilla/pkg/catalog"
 	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/process"
 	"github.com/1dustindavis/gorilla/pkg/report"
 	"github.com/1dustindavis/gorilla/pkg/service"
 )
@@ -20,11 +26,16 @@ func resetMainHooks() {
 	mkdirAllFunc = os.MkdirAll
 	buildCatalogsFunc = admin.BuildCatalogs
 	importItemFunc = admin.ImportItem
+	newReportFunc = report.New
 	managedRunFunc = managedRun
 	runServiceFunc = func(cfg config.Configuration) error { return service.Run(cfg, managedRunFunc) }
 	sendServiceCommandFunc = service.SendCommand
 	runServiceActionFunc = service.RunAction
 	serviceStatusFunc = service.ServiceStatus
+	showReportFunc = showReport
+	processInstallsFunc = process.Installs
+	processUninstallsFunc = process.Uninstalls
+	processUpdatesFunc = process.Updates
 }
 
 func TestRunAdminCheckError(t *testing.T) {
@@ -126,7 +137,7 @@ func TestRunBuildMode(t *testing.T) {
 		return false, nil
 	}
 	mkdirAllFunc = func(path string, mode os.FileMode) error { return nil }
-	buildCatalogsFunc = func(repoPath string) error {
+	buildCatalogsFunc = func(repoPath, signingKeyPath string) error {
 		buildCalled = true
 		if repoPath != "repo/path" {
 			t.Fatalf("unexpected repoPath: %s", repoPath)
@@ -160,7 +171,7 @@ func TestRunImportModeError(t *testing.T) {
 	}
 	adminCheckFunc = func() (bool, error) { return true, nil }
 	mkdirAllFunc = func(path string, mode os.FileMode) error { return nil }
-	buildCatalogsFunc = func(repoPath string) error { return nil }
+	buildCatalogsFunc = func(repoPath, signingKeyPath string) error { return nil }
 	importItemFunc = func(repoPath, itemPath string) error {
 		if repoPath != "repo/path" || itemPath != "x.msi" {
 			t.Fatalf("unexpected args repo=%s item=%s", repoPath, itemPath)
@@ -189,14 +200,13 @@ func TestManagedRunFinalizesReportOnManifestError(t *testing.T) {
 		Manifest:    "missing-manifest",
 	}
 
-	report.Items = make(map[string]interface{})
-	report.InstalledItems = nil
-	report.UninstalledItems = nil
+	var rpt *report.RunReport
+	newReportFunc = func(manifest string, catalogs []string) *report.RunReport {
+		rpt = report.New(manifest, catalogs)
+		return rpt
+	}
 	t.Cleanup(func() {
 		gorillalog.Close()
-		report.Items = make(map[string]interface{})
-		report.InstalledItems = nil
-		report.UninstalledItems = nil
 	})
 
 	adminCheckFunc = func() (bool, error) { return true, nil }
@@ -207,11 +217,66 @@ func TestManagedRunFinalizesReportOnManifestError(t *testing.T) {
 		t.Fatalf("expected error from manifest retrieval")
 	}
 
-	if _, ok := report.Items["EndTime"]; !ok {
+	if rpt == nil || rpt.EndTime == "" {
 		t.Fatalf("expected report EndTime to be set on manifest retrieval failure")
 	}
 }
 
+// TestManagedRunItemsFailed verifies a failed item does not stop the run, and is returned as errItemsFailed
+func TestManagedRunItemsFailed(t *testing.T) {
+	resetMainHooks()
+	defer resetMainHooks()
+	t.Cleanup(func() {
+		gorillalog.Close()
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifests/site.yaml":
+			fmt.Fprint(w, "name: site\ncatalogs: [production]\nmanaged_installs: [App]\nmanaged_updates: [Tool]\n")
+		case "/catalogs/production.yaml":
+			fmt.Fprint(w, "App:\n  installer: {type: msi, location: app.msi}\nTool:\n  installer: {type: msi, location: tool.msi}\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := config.Configuration{
+		CheckOnly:   true,
+		CachePath:   t.TempDir(),
+		AppDataPath: t.TempDir(),
+		URL:         ts.URL + "/",
+		Manifest:    "site",
+	}
+
+	processInstallsFunc = func(installs []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, checkOnly bool, rpt *report.RunReport) error {
+		return errors.New("App: installer failed")
+	}
+	updated := false
+	processUpdatesFunc = func(updates []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, checkOnly bool, rpt *report.RunReport) error {
+		updated = true
+		return nil
+	}
+
+	err := managedRun(cfg)
+	if !errors.Is(err, errItemsFailed) {
+		t.Fatalf("expected errItemsFailed, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "App: installer failed") {
+		t.Errorf("expected the item error to be included, got: %v", err)
+	}
+	if !updated {
+		t.Errorf("expected updates to be processed after an install failed")
+	}
+	if have, want := exitCode(err), 2; have != want {
+		t.Errorf("have exit code %d, want %d", have, want)
+	}
+	if have, want := exitCode(errors.New("unable to retrieve manifest")), 1; have != want {
+		t.Errorf("have exit code %d, want %d", have, want)
+	}
+}
+
 func TestExecuteServiceModesSkipRun(t *testing.T) {
 	resetMainHooks()
 	defer resetMainHooks()
@@ -604,3 +669,103 @@ func captureStdout(t *testing.T, fn func()) string {
 
 	return fmt.Sprint(buf.String())
 }
+
+func TestRouteReportListsAndPrintsHistory(t *testing.T) {
+	resetMainHooks()
+	defer resetMainHooks()
+
+	managedRunFunc = func(cfg config.Configuration) error {
+		t.Fatalf("managedRun should not run in report mode")
+		return nil
+	}
+
+	// Save two reports to the history
+	appDataPath := t.TempDir()
+	for _, manifest := range []string{"first_manifest", "second_manifest"} {
+		rpt := report.New(manifest, nil)
+		rpt.Add(report.ItemResult{Name: "ChefClient", Outcome: report.OutcomeFailed})
+		rpt.End(appDataPath, 5)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	cfg := config.Configuration{AppDataPath: appDataPath, ReportArg: "list"}
+	stdout := captureStdout(t, func() {
+		if err := route(cfg); err != nil {
+			t.Fatalf("unexpected route error: %v", err)
+		}
+	})
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 3 {
+		t.Fatalf("expected a header and two reports, got %q", stdout)
+	}
+
+	// 2 is the second most recent report
+	cfg.ReportArg = "2"
+	stdout = captureStdout(t, func() {
+		if err := route(cfg); err != nil {
+			t.Fatalf("unexpected route error: %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "first_manifest") {
+		t.Fatalf("expected the older report, got %q", stdout)
+	}
+
+	cfg.ReportArg = "3"
+	if err := route(cfg); err == nil {
+		t.Fatalf("expected an error for a report that does not exist")
+	}
+}
+
+func TestManagedRunPrefetchFailureContinues(t *testing.T) {
+	// The check script needs sh to report that App is needed
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	resetMainHooks()
+	defer resetMainHooks()
+	t.Cleanup(func() {
+		gorillalog.Close()
+	})
+
+	prefetched := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifests/site.yaml":
+			fmt.Fprint(w, "name: site\ncatalogs: [production]\nmanaged_installs: [App]\n")
+		case "/catalogs/production.yaml":
+			fmt.Fprint(w, "App:\n  script_interpreter: sh\n  check: {script: exit 0}\n  installer: {type: msi, location: app.msi, hash: "+strings.Repeat("0", 64)+"}\n")
+		case "/app.msi":
+			prefetched = true
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := config.Configuration{
+		CachePath:   t.TempDir(),
+		AppDataPath: t.TempDir(),
+		URL:         ts.URL + "/",
+		URLPackages: ts.URL + "/",
+		Manifest:    "site",
+	}
+
+	adminCheckFunc = func() (bool, error) { return true, nil }
+
+	// The package is missing, but the installs should still be processed
+	installed := false
+	processInstallsFunc = func(installs []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, checkOnly bool, rpt *report.RunReport) error {
+		installed = true
+		return nil
+	}
+
+	if err := managedRun(cfg); err != nil {
+		t.Fatalf("expected the run to continue after the prefetch failed, got: %v", err)
+	}
+	if !prefetched {
+		t.Errorf("expected the package to be prefetched")
+	}
+	if !installed {
+		t.Errorf("expected installs to be processed after the prefetch failed")
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/1dustindavis/gorilla/pkg/admin"
 	"github.com/1dustindavis/gorilla/pkg/catalog"
 	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/installer"
 	"github.com/1dustindavis/gorilla/pkg/manifest"
 	"github.com/1dustindavis/gorilla/pkg/process"
+	"github.com/1dustindavis/gorilla/pkg/reboot"
 	"github.com/1dustindavis/gorilla/pkg/report"
+	"github.com/1dustindavis/gorilla/pkg/status"
 )
 
 var (
@@ -21,8 +25,16 @@ var (
 	mkdirAllFunc      = os.MkdirAll
 	buildCatalogsFunc = admin.BuildCatalogs
 	importItemFunc    = admin.ImportItem
+	newReportFunc     = report.New
+
+	processInstallsFunc   = process.Installs
+	processUninstallsFunc = process.Uninstalls
+	processUpdatesFunc    = process.Updates
 )
 
+// errItemsFailed is returned when the run completed, but one or more items failed
+var errItemsFailed = errors.New("one or more items failed")
+
 func managedRun(cfg config.Configuration) error {
 	// Build/import modes operate on repo metadata and do not require admin.
 	buildMode := cfg.BuildArg || cfg.ImportArg != ""
@@ -50,7 +62,7 @@ func managedRun(cfg config.Configuration) error {
 
 	if cfg.BuildArg {
 		gorillalog.Info("Building catalogs...")
-		if err := buildCatalogsFunc(cfg.RepoPath); err != nil {
+		if err := buildCatalogsFunc(cfg.RepoPath, cfg.SigningPrivateKey); err != nil {
 			return fmt.Errorf("error building catalogs: %w", err)
 		}
 		return nil
@@ -65,13 +77,27 @@ func managedRun(cfg config.Configuration) error {
 	}
 
 	// Start creating GorillaReport
+	rpt := newReportFunc(cfg.Manifest, cfg.Catalogs)
 	if !cfg.CheckOnly {
-		report.Start()
-		defer report.End()
+		defer func() {
+			rpt.RebootRequired = reboot.IsPending(cfg.AppDataPath)
+			rpt.End(cfg.AppDataPath, cfg.ReportHistory)
+
+			// Send the report to a central collector if one is configured
+			if cfg.ReportURL != "" {
+				// Spool at least this report, even if the history is turned off
+				gorillalog.Info("Uploading GorillaReport to", cfg.ReportURL)
+				if err := rpt.Upload(cfg.ReportURL, cfg.AppDataPath, max(cfg.ReportHistory, 1)); err != nil {
+					gorillalog.Warn("Unable to upload report, it will be retried on the next run:", err)
+				}
+			}
+		}()
 	}
 
-	// Set the configuration that `download` will use
+	// Set the configuration that `download`, `installer` and `status` will use
 	download.SetConfig(cfg)
+	installer.SetConfig(cfg)
+	status.SetConfig(cfg)
 
 	// Get the manifests
 	gorillalog.Info("Retrieving manifest:", cfg.Manifest)
@@ -83,6 +109,7 @@ func managedRun(cfg config.Configuration) error {
 	// If we have newCatalogs, add them to the configuration
 	if newCatalogs != nil {
 		cfg.Catalogs = append(cfg.Catalogs, newCatalogs...)
+		rpt.Catalogs = cfg.Catalogs
 	}
 
 	// Get the catalogs
@@ -96,28 +123,85 @@ func managedRun(cfg config.Configuration) error {
 	gorillalog.Info("Processing manifest...")
 	installs, uninstalls, updates := process.Manifests(manifests, catalogs)
 
+	// In plan mode, describe what would change and stop before making any changes
+	if cfg.PlanArg {
+		gorillalog.Info("Building plan...")
+		plan := process.BuildPlan(installs, uninstalls, updates, catalogs, cfg.Catalogs, cfg.CachePath)
+		return writePlan(plan, cfg.PlanFile)
+	}
+
+	// Download every package we need before making any changes. A package that could not be
+	// downloaded is tried again when its item is installed, and the failure is recorded there.
+	if !cfg.CheckOnly {
+		gorillalog.Info("Prefetching packages...")
+		plan := process.BuildPlan(installs, uninstalls, updates, catalogs, cfg.Catalogs, cfg.CachePath)
+		if err := process.Prefetch(plan, catalogs, cfg.URLPackages, cfg.CachePath, cfg.PrefetchParallelism); err != nil {
+			gorillalog.Warn("Unable to prefetch packages, continuing:", err)
+		}
+	}
+
 	// Prepare and install
 	gorillalog.Info("Processing managed installs...")
-	process.Installs(installs, catalogs, cfg.URLPackages, cfg.CachePath, cfg.CheckOnly)
+	installErr := processInstallsFunc(installs, catalogs, cfg.URLPackages, cfg.CachePath, cfg.CheckOnly, rpt)
 
 	// Prepare and uninstall
 	gorillalog.Info("Processing managed uninstalls...")
-	process.Uninstalls(uninstalls, catalogs, cfg.URLPackages, cfg.CachePath, cfg.CheckOnly)
+	uninstallErr := processUninstallsFunc(uninstalls, installs, catalogs, cfg.URLPackages, cfg.CachePath, cfg.CheckOnly, rpt)
 
 	// Prepare and update
 	gorillalog.Info("Processing managed updates...")
-	process.Updates(updates, catalogs, cfg.URLPackages, cfg.CachePath, cfg.CheckOnly)
+	updateErr := processUpdatesFunc(updates, catalogs, cfg.URLPackages, cfg.CachePath, cfg.CheckOnly, rpt)
 
 	// Save GorillaReport to disk
 	gorillalog.Info("Saving GorillaReport.json...")
 	if cfg.CheckOnly {
-		report.Print()
+		rpt.Print()
 	}
 
-	// Run CleanUp to delete old cached items and empty directories
+	// Run CleanUp to delete old cached items and empty directories, keeping anything still managed
 	gorillalog.Info("Cleaning up the cache...")
-	process.CleanUp(cfg.CachePath)
+	// An invalid limit is logged and not enforced
+	maxAge, err := time.ParseDuration(cfg.CacheMaxAge)
+	if err != nil {
+		gorillalog.Warn("Invalid cache_max_age, not removing old packages:", err)
+	}
+	var maxSize int64
+	if cfg.CacheMaxSize != "" {
+		if maxSize, err = config.ParseSize(cfg.CacheMaxSize); err != nil {
+			gorillalog.Warn("Invalid cache_max_size, not limiting the cache size:", err)
+		}
+	}
+	keep := process.ManagedPackages(installs, uninstalls, updates, catalogs, cfg.CachePath)
+	process.CleanUp(cfg.CachePath, keep, maxAge, maxSize)
+
+	// Report every item that failed once the run is complete
+	if err := errors.Join(installErr, uninstallErr, updateErr); err != nil {
+		gorillalog.Warn("Done, but one or more items failed:", err)
+		return fmt.Errorf("%w: %w", errItemsFailed, err)
+	}
 
 	gorillalog.Info("Done!")
 	return nil
 }
+
+// writePlan prints the plan as a table and, if requested, saves it as a JSON document
+func writePlan(plan process.Plan, planFile string) error {
+	if err := plan.WriteTable(os.Stdout); err != nil {
+		return fmt.Errorf("unable to print plan: %w", err)
+	}
+
+	if planFile == "" {
+		return nil
+	}
+
+	file, err := os.Create(planFile)
+	if err != nil {
+		return fmt.Errorf("unable to create plan file: %w", err)
+	}
+	defer file.Close()
+
+	if err := plan.WriteJSON(file); err != nil {
+		return fmt.Errorf("unable to write plan file: %w", err)
+	}
+	return nil
+}
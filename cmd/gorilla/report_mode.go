@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+	"github.com/1dustindavis/gorilla/pkg/report"
+)
+
+// showReport lists the saved run reports, or prints a single report from the history
+func showReport(cfg config.Configuration) error {
+	reports, err := report.History(cfg.AppDataPath)
+	if err != nil {
+		return fmt.Errorf("unable to read report history: %w", err)
+	}
+
+	if cfg.ReportArg == "list" {
+		if len(reports) == 0 {
+			fmt.Println("No saved reports")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tSTART\tEND\tSUCCEEDED\tFAILED\tPATH")
+		for i, path := range reports {
+			rpt, err := report.Load(path)
+			if err != nil {
+				fmt.Fprintf(tw, "%d\t-\t-\t-\t-\t%s (%v)\n", i+1, path, err)
+				continue
+			}
+			counts := rpt.Count()
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%s\n", i+1, rpt.StartTime, rpt.EndTime, counts[report.OutcomeSucceeded], counts[report.OutcomeFailed], path)
+		}
+		return tw.Flush()
+	}
+
+	index, err := strconv.Atoi(cfg.ReportArg)
+	if err != nil || index < 1 {
+		return fmt.Errorf("invalid report %q: use 'list' or a number where 1 is the most recent", cfg.ReportArg)
+	}
+	if index > len(reports) {
+		return fmt.Errorf("report %d not found: %d saved reports", index, len(reports))
+	}
+
+	rpt, err := report.Load(reports[index-1])
+	if err != nil {
+		return err
+	}
+	rpt.Print()
+	return nil
+}
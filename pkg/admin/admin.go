@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"crypto/ed25519"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/1dustindavis/gorilla/pkg/catalog"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/signing"
 	"go.yaml.in/yaml/v4"
 )
 
@@ -18,7 +20,9 @@ type packageInfo struct {
 }
 
 // BuildCatalogs compiles package-info files from <repo>/packages-info into <repo>/catalogs.
-func BuildCatalogs(repoPath string) error {
+// If `signingKeyPath` is set, a detached signature is written next to each catalog,
+// and next to each manifest in <repo>/manifests.
+func BuildCatalogs(repoPath string, signingKeyPath string) error {
 	packagesInfoPath := filepath.Join(repoPath, "packages-info")
 	catalogsPath := filepath.Join(repoPath, "catalogs")
 
@@ -26,6 +30,16 @@ func BuildCatalogs(repoPath string) error {
 		return fmt.Errorf("packages-info path unavailable: %w", err)
 	}
 
+	// Load the signing key before changing anything
+	var signingKey ed25519.PrivateKey
+	if signingKeyPath != "" {
+		var err error
+		signingKey, err = signing.LoadPrivateKey(signingKeyPath)
+		if err != nil {
+			return fmt.Errorf("load signing key: %w", err)
+		}
+	}
+
 	var packageInfoQueue []string
 	err := filepath.WalkDir(packagesInfoPath, func(path string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
@@ -96,11 +110,50 @@ func BuildCatalogs(repoPath string) error {
 		if err := os.WriteFile(catalogPath, catalogYAML, 0644); err != nil {
 			return fmt.Errorf("write catalog %s: %w", catalogPath, err)
 		}
+		if signingKey != nil {
+			if err := writeSignature(catalogPath, catalogYAML, signingKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	if signingKey != nil {
+		return signManifests(filepath.Join(repoPath, "manifests"), signingKey)
 	}
+	return nil
+}
 
+// writeSignature writes a detached signature of `data` next to `path`
+func writeSignature(path string, data []byte, key ed25519.PrivateKey) error {
+	signaturePath := path + signing.Extension
+	gorillalog.Debug("Writing signature:", signaturePath)
+	if err := os.WriteFile(signaturePath, signing.Sign(key, data), 0644); err != nil {
+		return fmt.Errorf("write signature %s: %w", signaturePath, err)
+	}
 	return nil
 }
 
+// signManifests writes a detached signature next to each manifest in `manifestsPath`
+func signManifests(manifestsPath string, key ed25519.PrivateKey) error {
+	if _, err := os.Stat(manifestsPath); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(manifestsPath, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			gorillalog.Warn("Failed to access path:", path, walkErr)
+			return walkErr
+		}
+		if d.IsDir() || strings.ToLower(filepath.Ext(path)) != ".yaml" {
+			return nil
+		}
+		manifestYAML, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read manifest %s: %w", path, err)
+		}
+		return writeSignature(path, manifestYAML, key)
+	})
+}
+
 // ImportItem converts a package into package-info data.
 func ImportItem(repoPath string, itemPath string) error {
 	return fmt.Errorf("import is not yet implemented (repoPath=%s, itemPath=%s)", repoPath, itemPath)
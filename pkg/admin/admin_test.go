@@ -1,6 +1,10 @@
 package admin
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -53,7 +57,7 @@ installer:
 		t.Fatal(err)
 	}
 
-	if err := BuildCatalogs(repoPath); err != nil {
+	if err := BuildCatalogs(repoPath, ""); err != nil {
 		t.Fatalf("BuildCatalogs failed: %v", err)
 	}
 
@@ -79,7 +83,7 @@ installer:
 
 func TestBuildCatalogsMissingPackagesInfo(t *testing.T) {
 	repoPath := t.TempDir()
-	if err := BuildCatalogs(repoPath); err == nil {
+	if err := BuildCatalogs(repoPath, ""); err == nil {
 		t.Fatalf("expected error when packages-info is missing")
 	}
 }
@@ -109,7 +113,7 @@ version: 1.2.3.4
 		t.Fatal(err)
 	}
 
-	if err := BuildCatalogs(repoPath); err != nil {
+	if err := BuildCatalogs(repoPath, ""); err != nil {
 		t.Fatalf("BuildCatalogs failed: %v", err)
 	}
 
@@ -145,3 +149,89 @@ version: 1.2.3.4
 		t.Fatalf("unexpected version: %s", chrome.Version)
 	}
 }
+
+func TestBuildCatalogsSigned(t *testing.T) {
+	repoPath := t.TempDir()
+	packagesInfoPath := filepath.Join(repoPath, "packages-info")
+	manifestsPath := filepath.Join(repoPath, "manifests")
+	for _, dir := range []string{packagesInfoPath, manifestsPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	item := `
+item_name: Chrome
+display_name: Google Chrome
+catalog: base
+installer:
+  type: msi
+  location: packages/chrome/chrome.msi
+  hash: abc
+`
+	if err := os.WriteFile(filepath.Join(packagesInfoPath, "chrome.yaml"), []byte(item), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(manifestsPath, "example.yaml"), []byte("managed_installs:\n  - Chrome\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Generate a signing key pair
+	keyPath := t.TempDir()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath := filepath.Join(keyPath, "signing.key")
+	publicPath := filepath.Join(keyPath, "signing.pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BuildCatalogs(repoPath, privatePath); err != nil {
+		t.Fatalf("BuildCatalogs failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(manifestsPath, "example.yaml.sig")); err != nil {
+		t.Fatalf("expected the manifest to be signed: %v", err)
+	}
+
+	handler := http.NewServeMux()
+	handler.Handle("/catalogs/", http.StripPrefix("/catalogs/", http.FileServer(http.Dir(filepath.Join(repoPath, "catalogs")))))
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := config.Configuration{
+		URL:               ts.URL + "/",
+		Catalogs:          []string{"base"},
+		SigningPublicKeys: []string{publicPath},
+		RequireSignatures: true,
+	}
+	if _, err := catalog.Get(cfg); err != nil {
+		t.Fatalf("catalog.Get failed with a valid signature: %v", err)
+	}
+
+	// A catalog changed after it was signed should be rejected
+	catalogPath := filepath.Join(repoPath, "catalogs", "base.yaml")
+	f, err := os.OpenFile(catalogPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("Malware:\n  installer:\n    type: exe\n    location: evil.exe\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := catalog.Get(cfg); err == nil {
+		t.Fatalf("expected catalog.Get to reject a modified catalog")
+	}
+}
@@ -7,29 +7,45 @@ import (
 	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/signing"
 	"go.yaml.in/yaml/v4"
 )
 
 // Item contains an individual entry from the catalog
 type Item struct {
-	Dependencies []string      `yaml:"dependencies"`
-	DisplayName  string        `yaml:"display_name"`
-	Check        InstallCheck  `yaml:"check"`
-	Installer    InstallerItem `yaml:"installer"`
-	Uninstaller  InstallerItem `yaml:"uninstaller"`
-	Version      string        `yaml:"version"`
-	BlockingApps []string      `yaml:"blocking_apps"`
-	PreScript    string        `yaml:"preinstall_script"`
-	PostScript   string        `yaml:"postinstall_script"`
+	// Name is the key the item was loaded from; it is not part of the catalog yaml
+	Name                string        `yaml:"-"`
+	Dependencies        []string      `yaml:"dependencies"`
+	DisplayName         string        `yaml:"display_name"`
+	Check               InstallCheck  `yaml:"check"`
+	Installer           InstallerItem `yaml:"installer"`
+	Uninstaller         InstallerItem `yaml:"uninstaller"`
+	Version             string        `yaml:"version"`
+	BlockingApps        []string      `yaml:"blocking_apps"`
+	BlockingAppsAction  string        `yaml:"blocking_apps_action"`
+	BlockingAppsTimeout string        `yaml:"blocking_apps_timeout"`
+	PreScript           string        `yaml:"preinstall_script"`
+	PostScript          string        `yaml:"postinstall_script"`
+	PreUninstallScript  string        `yaml:"preuninstall_script"`
+	PostUninstallScript string        `yaml:"postuninstall_script"`
+	ScriptInterpreter   string        `yaml:"script_interpreter"`
+	InstallerTimeout    string        `yaml:"installer_timeout"`
+	ScriptTimeout       string        `yaml:"script_timeout"`
+	RestartAction       string        `yaml:"restart_action"`
+	RequiresCleanBoot   bool          `yaml:"requires_clean_boot"`
 }
 
 // InstallerItem holds information about how to install a catalog item
 type InstallerItem struct {
-	Type      string   `yaml:"type"`
-	Location  string   `yaml:"location"`
-	Hash      string   `yaml:"hash"`
-	PackageID string   `yaml:"package_id"`
-	Arguments []string `yaml:"arguments"`
+	Type             string   `yaml:"type"`
+	Location         string   `yaml:"location"`
+	Hash             string   `yaml:"hash"`
+	PackageID        string   `yaml:"package_id"`
+	Arguments        []string `yaml:"arguments"`
+	SuccessExitCodes []int    `yaml:"success_exit_codes,omitempty"`
+	RebootExitCodes  []int    `yaml:"reboot_exit_codes,omitempty"`
+	// Script is run in place of a package when the type is "nopkg"
+	Script string `yaml:"script,omitempty"`
 }
 
 // InstallCheck holds information about how to check the status of a catalog item
@@ -54,7 +70,7 @@ type RegCheck struct {
 }
 
 // This abstraction allows us to override the function while testing
-var downloadGet = download.Get
+var downloadGet = download.Cached
 
 // Get returns a map of `Item` from the catalog and any fatal catalog-loading error.
 func Get(cfg config.Configuration) (map[int]map[string]Item, error) {
@@ -70,6 +86,12 @@ func Get(cfg config.Configuration) (map[int]map[string]Item, error) {
 		return nil, errors.New("unable to continue, no catalogs assigned")
 	}
 
+	// Load the keys used to verify each catalog before we parse it
+	verifier, err := signing.NewVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Loop through the catalogs and get each one in order
 	for _, catalog := range cfg.Catalogs {
 
@@ -80,6 +102,9 @@ func Get(cfg config.Configuration) (map[int]map[string]Item, error) {
 		if err != nil {
 			return nil, fmt.Errorf("unable to retrieve catalog %s: %w", catalogURL, err)
 		}
+		if err := verifier.VerifyURL(catalogURL, yamlFile, downloadGet); err != nil {
+			return nil, fmt.Errorf("unable to verify catalog %s: %w", catalogURL, err)
+		}
 
 		// Parse the catalog
 		var catalogItems map[string]Item
@@ -88,6 +113,12 @@ func Get(cfg config.Configuration) (map[int]map[string]Item, error) {
 			return nil, fmt.Errorf("unable to parse yaml catalog %s: %w", catalogURL, err)
 		}
 
+		// Keep track of the name each item was loaded from
+		for name, item := range catalogItems {
+			item.Name = name
+			catalogItems[name] = item
+		}
+
 		catalogCount++
 
 		// Add the new parsed catalog items to the catalogMap
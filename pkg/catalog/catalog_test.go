@@ -41,6 +41,7 @@ func TestGet(t *testing.T) {
 	expected = make(map[string]Item)
 	// Set what we expect Get() to return
 	expected[`ChefClient`] = Item{
+		Name:         `ChefClient`,
 		Dependencies: []string{`ruby`},
 		DisplayName:  "Chef Client",
 		Check: InstallCheck{
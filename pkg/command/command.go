@@ -0,0 +1,112 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+)
+
+// ErrTimeout is returned when a command runs longer than its timeout
+var ErrTimeout = errors.New("command timed out")
+
+// waitDelay is how long `Wait` waits for output after the command exits, in case
+// a process it started is still holding stdout or stderr open
+const waitDelay = 10 * time.Second
+
+// Process is a running command. If it runs longer than its timeout, the command and
+// every process it started are killed.
+type Process struct {
+	cmd     *exec.Cmd
+	timeout time.Duration
+	timer   *time.Timer
+	tree    processTree
+	killed  chan struct{} // closed once a timed out command has been killed
+}
+
+// Start starts `cmd` in its own process tree. A timeout of 0 means no limit.
+func Start(cmd *exec.Cmd, timeout time.Duration) (*Process, error) {
+	prepare(cmd)
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = waitDelay
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &Process{cmd: cmd, timeout: timeout, killed: make(chan struct{})}
+	tree, err := attach(cmd)
+	if err != nil {
+		// The command still runs, but only it can be killed
+		gorillalog.Warn("Unable to track the processes started by", cmd.Path, err)
+	}
+	p.tree = tree
+
+	// The command can only start once it is being tracked
+	if err := resume(cmd); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		tree.release()
+		return nil, fmt.Errorf("unable to resume %s: %w", cmd.Path, err)
+	}
+
+	if timeout > 0 {
+		p.timer = time.AfterFunc(timeout, p.kill)
+	}
+	return p, nil
+}
+
+// kill stops the command and every process it started
+func (p *Process) kill() {
+	defer close(p.killed)
+	gorillalog.Warn("Command timed out after", p.timeout, "killing it and any processes it started:", p.cmd.Path)
+	if err := p.tree.kill(p.cmd); err != nil {
+		gorillalog.Warn("Unable to kill process tree:", err)
+	}
+}
+
+// Wait waits for the command to exit. If it timed out, the error wraps `ErrTimeout`.
+func (p *Process) Wait() error {
+	err := p.cmd.Wait()
+
+	// If the timer already fired, wait for the kill to finish before releasing the process tree
+	timedOut := p.timer != nil && !p.timer.Stop()
+	if timedOut {
+		<-p.killed
+	}
+	p.tree.release()
+
+	if timedOut {
+		return fmt.Errorf("%w after %s", ErrTimeout, p.timeout)
+	}
+	return err
+}
+
+// Run starts `cmd` and waits for it to exit, killing it and every process it started if it
+// runs longer than `timeout`. A timeout of 0 means no limit.
+func Run(cmd *exec.Cmd, timeout time.Duration) error {
+	p, err := Start(cmd, timeout)
+	if err != nil {
+		return err
+	}
+	return p.Wait()
+}
+
+// Timeout returns the first of `values` that is set, as a duration. Callers pass an item's
+// own timeout before the global one. An invalid value is logged and skipped.
+func Timeout(values ...string) time.Duration {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			gorillalog.Warn("Invalid timeout:", value, err)
+			continue
+		}
+		return timeout
+	}
+	return 0
+}
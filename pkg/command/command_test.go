@@ -0,0 +1,54 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestRunTimeout verifies a command that runs too long is killed along with the processes it started
+func TestRunTimeout(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// The background sleep keeps stdout open, so Run only returns quickly if it is killed too
+	cmd := exec.Command(sh, "-c", "sleep 30 & sleep 30")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	start := time.Now()
+	err = Run(cmd, 100*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected a timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the process tree to be killed, Run took %s", elapsed)
+	}
+
+	// A command that finishes in time returns its own result
+	if err := Run(exec.Command(sh, "-c", "exit 3"), time.Minute); errors.Is(err, ErrTimeout) || err == nil {
+		t.Errorf("Expected the exit error, got: %v", err)
+	}
+}
+
+// TestTimeout verifies the first valid timeout is used
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   time.Duration
+	}{
+		{values: []string{"5m", "1h"}, want: 5 * time.Minute},
+		{values: []string{"", "1h"}, want: time.Hour},
+		{values: []string{"soon", "10m"}, want: 10 * time.Minute},
+		{values: []string{"", ""}, want: 0},
+	}
+	for _, tt := range tests {
+		if have := Timeout(tt.values...); have != tt.want {
+			t.Errorf("%v: have %s, want %s", tt.values, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package command
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// processTree is the process group of a command
+type processTree struct{}
+
+// prepare starts the command in a new process group, so everything it starts can be killed together
+func prepare(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// attach returns the process tree of a started command
+func attach(cmd *exec.Cmd) (processTree, error) {
+	return processTree{}, nil
+}
+
+// resume does nothing, commands are not started suspended on this platform
+func resume(cmd *exec.Cmd) error {
+	return nil
+}
+
+// kill stops every process in the command's process group
+func (processTree) kill(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// release frees anything used to track the process tree
+func (processTree) release() {}
@@ -0,0 +1,87 @@
+//go:build windows
+
+package command
+
+import (
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processTree is a job object holding a command and every process it starts
+type processTree struct {
+	job windows.Handle
+}
+
+// prepare starts the command suspended, so it can be added to a job object before it starts any processes
+func prepare(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+}
+
+// attach adds a started command to a new job object. Every process it starts is added to the job too.
+func attach(cmd *exec.Cmd) (processTree, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return processTree{}, err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return processTree{}, err
+	}
+	defer windows.CloseHandle(process)
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return processTree{}, err
+	}
+	return processTree{job: job}, nil
+}
+
+// resume starts the threads of a command that was started suspended
+func resume(cmd *exec.Cmd) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != uint32(cmd.Process.Pid) {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return err
+		}
+		_, err = windows.ResumeThread(thread)
+		windows.CloseHandle(thread)
+		if err != nil {
+			return err
+		}
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return err
+	}
+	return nil
+}
+
+// kill stops every process in the job, or only the command if it is not in a job
+func (t processTree) kill(cmd *exec.Cmd) error {
+	if t.job == 0 {
+		return cmd.Process.Kill()
+	}
+	return windows.TerminateJobObject(t.job, 1)
+}
+
+// release closes the job object. Processes that are still running are left alone.
+func (t processTree) release() {
+	if t.job != 0 {
+		windows.CloseHandle(t.job)
+	}
+}
@@ -3,12 +3,15 @@ package config
 import (
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.yaml.in/yaml/v4"
 
-	"github.com/1dustindavis/gorilla/pkg/report"
 	"github.com/1dustindavis/gorilla/pkg/version"
 )
 
@@ -27,6 +30,12 @@ var (
 	buildDefault      = false
 	importArg         string
 	importDefault     = ""
+	planArg           bool
+	planDefault       = false
+	planFileArg       string
+	planFileDefault   = ""
+	reportArg         string
+	reportDefault     = ""
 	helpArg           bool
 	helpDefault       = false
 	verboseArg        bool
@@ -44,6 +53,7 @@ var (
 	serviceStartArg   bool
 	serviceStopArg    bool
 	serviceStatusArg  bool
+	protectSecretArg  bool
 
 	// Use a fake function so we can override when testing
 	osExit = os.Exit
@@ -60,6 +70,9 @@ Options:
 -C, -checkonly	    enable check only mode
 -b, -build          build catalog files from package-info files
 -i, -import         create a package-info file from an installer package
+-plan               show what a run would change without making any changes
+-planfile           write the plan as a JSON document to this path (implies -plan)
+-report             list saved run reports, or print one (list|N, where 1 is the most recent)
 -v, -verbose        enable verbose output
 -d, -debug          enable debug output
 -a, -about          displays the version number and other build info
@@ -71,42 +84,84 @@ Options:
 -servicestart       start Gorilla Windows service
 -servicestop        stop Gorilla Windows service
 -servicestatus      show Gorilla Windows service status
+-protectsecret      read a secret from stdin and print a dpapi: reference to use in the configuration file
 -h, -help           display this help message
 
 `
 
 // Configuration stores all of the possible parameters a config file could contain
 type Configuration struct {
-	URL             string   `yaml:"url"`
-	URLPackages     string   `yaml:"url_packages"`
-	Manifest        string   `yaml:"manifest"`
-	LocalManifests  []string `yaml:"local_manifests,omitempty"`
-	Catalogs        []string `yaml:"catalogs"`
-	AppDataPath     string   `yaml:"app_data_path"`
-	Verbose         bool     `yaml:"verbose,omitempty"`
-	Debug           bool     `yaml:"debug,omitempty"`
-	CheckOnly       bool     `yaml:"checkonly,omitempty"`
-	BuildArg        bool
-	ImportArg       string
-	RepoPath        string `yaml:"repo_path,omitempty"`
-	AuthUser        string `yaml:"auth_user,omitempty"`
-	AuthPass        string `yaml:"auth_pass,omitempty"`
-	TLSAuth         bool   `yaml:"tls_auth,omitempty"`
-	TLSClientCert   string `yaml:"tls_client_cert,omitempty"`
-	TLSClientKey    string `yaml:"tls_client_key,omitempty"`
-	TLSServerCert   string `yaml:"tls_server_cert,omitempty"`
-	CachePath       string
-	ServiceMode     bool `yaml:"service_mode,omitempty"`
-	ServiceCommand  string
-	ServiceInstall  bool
-	ServiceRemove   bool
-	ServiceStart    bool
-	ServiceStop     bool
-	ServiceStatus   bool
-	ServiceName     string `yaml:"service_name,omitempty"`
-	ServiceInterval string `yaml:"service_interval,omitempty"`
-	ServicePipeName string `yaml:"service_pipe_name,omitempty"`
-	ConfigPath      string
+	URL                      string   `yaml:"url"`
+	URLPackages              string   `yaml:"url_packages"`
+	Manifest                 string   `yaml:"manifest"`
+	LocalManifests           []string `yaml:"local_manifests,omitempty"`
+	Catalogs                 []string `yaml:"catalogs"`
+	AppDataPath              string   `yaml:"app_data_path"`
+	Verbose                  bool     `yaml:"verbose,omitempty"`
+	Debug                    bool     `yaml:"debug,omitempty"`
+	CheckOnly                bool     `yaml:"checkonly,omitempty"`
+	BuildArg                 bool
+	ImportArg                string
+	PlanArg                  bool
+	PlanFile                 string
+	ReportArg                string
+	ReportHistory            int               `yaml:"report_history,omitempty"`
+	ReportURL                string            `yaml:"report_url,omitempty"`
+	RepoPath                 string            `yaml:"repo_path,omitempty"`
+	AuthUser                 string            `yaml:"auth_user,omitempty"`
+	AuthPass                 string            `yaml:"auth_pass,omitempty"`
+	AuthToken                string            `yaml:"auth_token,omitempty"`
+	AuthTokenFile            string            `yaml:"auth_token_file,omitempty"`
+	AuthHeaders              map[string]string `yaml:"auth_headers,omitempty"`
+	OAuthTokenURL            string            `yaml:"oauth_token_url,omitempty"`
+	OAuthClientID            string            `yaml:"oauth_client_id,omitempty"`
+	OAuthClientSecret        string            `yaml:"oauth_client_secret,omitempty"`
+	OAuthScopes              []string          `yaml:"oauth_scopes,omitempty"`
+	TLSAuth                  bool              `yaml:"tls_auth,omitempty"`
+	TLSClientCert            string            `yaml:"tls_client_cert,omitempty"`
+	TLSClientKey             string            `yaml:"tls_client_key,omitempty"`
+	TLSServerCert            string            `yaml:"tls_server_cert,omitempty"`
+	DownloadRetries          int               `yaml:"download_retries,omitempty"`
+	DownloadBackoff          string            `yaml:"download_backoff,omitempty"`
+	DownloadJitter           string            `yaml:"download_jitter,omitempty"`
+	DownloadRetryStatusCodes []int             `yaml:"download_retry_status_codes,omitempty"`
+	MetadataTimeout          string            `yaml:"metadata_timeout,omitempty"`
+	PackageTimeout           string            `yaml:"package_timeout,omitempty"`
+	MetadataMaxStaleness     string            `yaml:"metadata_max_staleness,omitempty"`
+	PrefetchParallelism      int               `yaml:"prefetch_parallelism,omitempty"`
+	SigningPublicKeys        []string          `yaml:"signing_public_keys,omitempty"`
+	RequireSignatures        bool              `yaml:"require_signatures,omitempty"`
+	SigningPrivateKey        string            `yaml:"signing_private_key,omitempty"`
+	HashlessItems            string            `yaml:"hashless_items,omitempty"`
+	CacheMaxAge              string            `yaml:"cache_max_age,omitempty"`
+	CacheMaxSize             string            `yaml:"cache_max_size,omitempty"`
+	PackageSources           []string          `yaml:"package_sources,omitempty"`
+	DownloadRateLimit        string            `yaml:"download_rate_limit,omitempty"`
+	ServiceDownloadRateLimit string            `yaml:"service_download_rate_limit,omitempty"`
+	FullSpeedHours           string            `yaml:"full_speed_hours,omitempty"`
+	ProxyURL                 string            `yaml:"proxy_url,omitempty"`
+	NoProxy                  []string          `yaml:"no_proxy,omitempty"`
+	ProxyUser                string            `yaml:"proxy_user,omitempty"`
+	ProxyPass                string            `yaml:"proxy_pass,omitempty"`
+	S3Endpoint               string            `yaml:"s3_endpoint,omitempty"`
+	S3Region                 string            `yaml:"s3_region,omitempty"`
+	S3AccessKeyID            string            `yaml:"s3_access_key_id,omitempty"`
+	S3SecretAccessKey        string            `yaml:"s3_secret_access_key,omitempty"`
+	S3SessionToken           string            `yaml:"s3_session_token,omitempty"`
+	InstallerTimeout         string            `yaml:"installer_timeout,omitempty"`
+	ScriptTimeout            string            `yaml:"script_timeout,omitempty"`
+	CachePath                string
+	ServiceMode              bool `yaml:"service_mode,omitempty"`
+	ServiceCommand           string
+	ServiceInstall           bool
+	ServiceRemove            bool
+	ServiceStart             bool
+	ServiceStop              bool
+	ServiceStatus            bool
+	ServiceName              string `yaml:"service_name,omitempty"`
+	ServiceInterval          string `yaml:"service_interval,omitempty"`
+	ServicePipeName          string `yaml:"service_pipe_name,omitempty"`
+	ConfigPath               string
 }
 
 func init() {
@@ -127,6 +182,11 @@ func init() {
 	// Import
 	flag.StringVar(&importArg, "import", importDefault, "")
 	flag.StringVar(&importArg, "i", importDefault, "")
+	// Plan
+	flag.BoolVar(&planArg, "plan", planDefault, "")
+	flag.StringVar(&planFileArg, "planfile", planFileDefault, "")
+	// Report
+	flag.StringVar(&reportArg, "report", reportDefault, "")
 	// Checkonly
 	flag.BoolVar(&checkOnlyArg, "checkonly", checkOnlyDefault, "")
 	flag.BoolVar(&checkOnlyArg, "C", checkOnlyDefault, "")
@@ -151,6 +211,8 @@ func init() {
 	flag.BoolVar(&serviceStartArg, "servicestart", false, "")
 	flag.BoolVar(&serviceStopArg, "servicestop", false, "")
 	flag.BoolVar(&serviceStatusArg, "servicestatus", false, "")
+	// Protect a secret for the configuration file
+	flag.BoolVar(&protectSecretArg, "protectsecret", false, "")
 }
 
 func parseArguments() (string, bool, bool, bool, bool, string) {
@@ -169,6 +231,16 @@ func parseArguments() (string, bool, bool, bool, bool, string) {
 		version.PrintFull()
 		osExit(0)
 	}
+	if protectSecretArg {
+		fmt.Fprintln(os.Stderr, "Enter the secret to protect:")
+		reference, err := protectSecret(os.Stdin)
+		if err != nil {
+			fmt.Println("Unable to protect secret:", err)
+			osExit(1)
+		}
+		fmt.Println(reference)
+		osExit(0)
+	}
 
 	return configArg, verboseArg, debugArg, checkOnlyArg, buildArg, importArg
 }
@@ -194,11 +266,18 @@ func Get() Configuration {
 		osExit(1)
 	}
 
+	// Replace references to secrets with the secrets themselves
+	if err := resolveSecrets(&cfg); err != nil {
+		fmt.Println("Unable to resolve configuration secrets:", err)
+		osExit(1)
+	}
+
 	serviceControlMode := serviceInstallArg || serviceRemoveArg || serviceStartArg || serviceStopArg || serviceStatusArg
 	serviceClientMode := serviceCmdArg != ""
+	reportMode := reportArg != ""
 
 	// Normal run mode requires both manifest and URL.
-	if !cfg.BuildArg && cfg.ImportArg == "" && !serviceControlMode && !serviceClientMode {
+	if !cfg.BuildArg && cfg.ImportArg == "" && !serviceControlMode && !serviceClientMode && !reportMode {
 		if cfg.Manifest == "" {
 			fmt.Println("Invalid configuration - Manifest: ", err)
 			osExit(1)
@@ -238,6 +317,14 @@ func Get() Configuration {
 	}
 	cfg.BuildArg = build
 	cfg.ImportArg = importValue
+
+	// Plan mode never makes changes, so it always runs as check only
+	cfg.PlanArg = planArg || planFileArg != ""
+	cfg.PlanFile = planFileArg
+	if cfg.PlanArg {
+		cfg.CheckOnly = true
+	}
+	cfg.ReportArg = reportArg
 	cfg.ConfigPath = configPath
 	cfg.ServiceMode = serviceArg
 	cfg.ServiceCommand = serviceCmdArg
@@ -271,9 +358,124 @@ func Get() Configuration {
 		cfg.RepoPath = filepath.Clean(cfg.RepoPath)
 	}
 
-	// Add to GorillaReport
-	report.Items["Manifest"] = cfg.Manifest
-	report.Items["Catalog"] = cfg.Catalogs
+	// Configure download defaults. A negative number of retries disables retrying.
+	if cfg.DownloadRetries == 0 {
+		cfg.DownloadRetries = 3
+	} else if cfg.DownloadRetries < 0 {
+		cfg.DownloadRetries = 0
+	}
+	if cfg.DownloadBackoff == "" {
+		cfg.DownloadBackoff = "1s"
+	}
+	if cfg.DownloadJitter == "" {
+		cfg.DownloadJitter = "500ms"
+	}
+	if cfg.DownloadRetryStatusCodes == nil {
+		cfg.DownloadRetryStatusCodes = []int{408, 429, 500, 502, 503, 504}
+	}
+	if cfg.MetadataTimeout == "" {
+		cfg.MetadataTimeout = "2m"
+	}
+	if cfg.PackageTimeout == "" {
+		cfg.PackageTimeout = "1h"
+	}
+
+	// Installers and scripts that hang are killed so the rest of the run can continue
+	if cfg.InstallerTimeout == "" {
+		cfg.InstallerTimeout = "1h"
+	}
+	if cfg.ScriptTimeout == "" {
+		cfg.ScriptTimeout = "10m"
+	}
+
+	// Cached manifests and catalogs can be used for a week after the server was last reachable
+	if cfg.MetadataMaxStaleness == "" {
+		cfg.MetadataMaxStaleness = "168h"
+	}
+
+	// Remove cached packages that have not been used for 5 days, with no size limit
+	if cfg.CacheMaxAge == "" {
+		cfg.CacheMaxAge = "120h"
+	}
+	for name, value := range map[string]string{
+		"cache_max_size":              cfg.CacheMaxSize,
+		"download_rate_limit":         cfg.DownloadRateLimit,
+		"service_download_rate_limit": cfg.ServiceDownloadRateLimit,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := ParseSize(value); err != nil {
+			fmt.Printf("Invalid configuration - %s: %v\n", name, err)
+			osExit(1)
+		}
+	}
+	if cfg.FullSpeedHours != "" {
+		if _, _, err := ParseHours(cfg.FullSpeedHours); err != nil {
+			fmt.Printf("Invalid configuration - full_speed_hours: %v\n", err)
+			osExit(1)
+		}
+	}
+	if cfg.OAuthTokenURL != "" && (cfg.OAuthClientID == "" || cfg.OAuthClientSecret == "") {
+		fmt.Println("Invalid configuration - oauth_token_url requires oauth_client_id and oauth_client_secret")
+		osExit(1)
+	}
+	if cfg.S3Endpoint != "" {
+		if endpoint, err := url.Parse(cfg.S3Endpoint); err != nil || endpoint.Host == "" {
+			fmt.Println("Invalid configuration - s3_endpoint must be a url like http://minio.example.com:9000:", cfg.S3Endpoint)
+			osExit(1)
+		}
+	}
+	if cfg.ProxyURL != "" {
+		if proxy, err := url.Parse(cfg.ProxyURL); err != nil || proxy.Host == "" {
+			fmt.Println("Invalid configuration - proxy_url must be a url like http://proxy.example.com:8080:", cfg.ProxyURL)
+			osExit(1)
+		}
+	}
+	for name, value := range map[string]string{
+		"download_backoff":       cfg.DownloadBackoff,
+		"download_jitter":        cfg.DownloadJitter,
+		"metadata_timeout":       cfg.MetadataTimeout,
+		"package_timeout":        cfg.PackageTimeout,
+		"installer_timeout":      cfg.InstallerTimeout,
+		"script_timeout":         cfg.ScriptTimeout,
+		"metadata_max_staleness": cfg.MetadataMaxStaleness,
+		"cache_max_age":          cfg.CacheMaxAge,
+	} {
+		if _, err := time.ParseDuration(value); err != nil {
+			fmt.Printf("Invalid configuration - %s: %v\n", name, err)
+			osExit(1)
+		}
+	}
+
+	// Signatures can not be required without a key to check them against
+	if cfg.RequireSignatures && len(cfg.SigningPublicKeys) == 0 {
+		fmt.Println("Invalid configuration - require_signatures: no signing_public_keys are configured")
+		osExit(1)
+	}
+
+	// Packages without a hash are rejected unless they are explicitly allowed
+	switch cfg.HashlessItems {
+	case "":
+		cfg.HashlessItems = "reject"
+	case "reject", "allow":
+	default:
+		fmt.Printf("Invalid configuration - hashless_items: %q must be \"reject\" or \"allow\"\n", cfg.HashlessItems)
+		osExit(1)
+	}
+
+	// Download up to 4 packages at a time before installing
+	if cfg.PrefetchParallelism < 1 {
+		cfg.PrefetchParallelism = 4
+	}
+
+	// Keep the last 10 reports unless configured otherwise, -1 turns the history off
+	if cfg.ReportHistory == 0 {
+		cfg.ReportHistory = 10
+	} else if cfg.ReportHistory < -1 {
+		fmt.Printf("Invalid configuration - report_history: %d must be -1 or more\n", cfg.ReportHistory)
+		osExit(1)
+	}
 
 	// Configure service defaults.
 	if cfg.ServiceName == "" {
@@ -288,3 +490,53 @@ func Get() Configuration {
 
 	return cfg
 }
+
+// sizeUnits are the suffixes accepted by `ParseSize`, largest first so "GB" is matched before "B"
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize converts a size like "500MB" or "1.5GB" to a number of bytes.
+// Units are powers of 1024, and a number without a unit is a number of bytes.
+func ParseSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := 1.0
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(number, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(size * multiplier), nil
+}
+
+// ParseHours converts a range of local times like "18:00-07:00" to the offset of its start and end
+// from midnight. The range may wrap past midnight.
+func ParseHours(value string) (start, end time.Duration, err error) {
+	startValue, endValue, found := strings.Cut(value, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid hours %q, expected a range like 18:00-07:00", value)
+	}
+	startTime, err := time.Parse("15:04", strings.TrimSpace(startValue))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hours %q: %w", value, err)
+	}
+	endTime, err := time.Parse("15:04", strings.TrimSpace(endValue))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hours %q: %w", value, err)
+	}
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return startTime.Sub(midnight), endTime.Sub(midnight), nil
+}
@@ -1,42 +1,61 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // TestGet tests that the configuration is retrieved and parsed properly
 func TestGet(t *testing.T) {
 	// Define what we expect in a successful test
 	expected := Configuration{
-		URL:             "https://example.com/gorilla/",
-		URLPackages:     "https://example.com/gorilla/",
-		Manifest:        "example_manifest",
-		LocalManifests:  []string{"example_local_manifest", filepath.Clean("c:/cpe/gorilla/service-manifest.yaml")},
-		Catalogs:        []string{"example_catalog"},
-		RepoPath:        filepath.Clean("c:/repo/gorilla"),
-		AppDataPath:     filepath.Clean("c:/cpe/gorilla/"),
-		Verbose:         true,
-		Debug:           true,
-		CheckOnly:       true,
-		BuildArg:        false,
-		ImportArg:       "",
-		AuthUser:        "johnny",
-		AuthPass:        "pizza",
-		CachePath:       filepath.Clean("c:/cpe/gorilla/cache"),
-		ServiceMode:     false,
-		ServiceCommand:  "",
-		ServiceInstall:  false,
-		ServiceRemove:   false,
-		ServiceStart:    false,
-		ServiceStop:     false,
-		ServiceStatus:   false,
-		ServiceName:     "gorilla",
-		ServiceInterval: "1h",
-		ServicePipeName: "gorilla-service",
-		ConfigPath:      "testdata/test_config.yaml",
+		URL:            "https://example.com/gorilla/",
+		URLPackages:    "https://example.com/gorilla/",
+		Manifest:       "example_manifest",
+		LocalManifests: []string{"example_local_manifest", filepath.Clean("c:/cpe/gorilla/service-manifest.yaml")},
+		Catalogs:       []string{"example_catalog"},
+		RepoPath:       filepath.Clean("c:/repo/gorilla"),
+		AppDataPath:    filepath.Clean("c:/cpe/gorilla/"),
+		Verbose:        true,
+		Debug:          true,
+		CheckOnly:      true,
+		BuildArg:       false,
+		ImportArg:      "",
+		PlanArg:        false,
+		PlanFile:       "",
+		ReportArg:      "",
+		ReportHistory:  10,
+		AuthUser:       "johnny",
+		AuthPass:       "pizza",
+		CachePath:      filepath.Clean("c:/cpe/gorilla/cache"),
+
+		DownloadRetries:          3,
+		DownloadBackoff:          "1s",
+		DownloadJitter:           "500ms",
+		DownloadRetryStatusCodes: []int{408, 429, 500, 502, 503, 504},
+		MetadataTimeout:          "2m",
+		PackageTimeout:           "1h",
+		InstallerTimeout:         "1h",
+		ScriptTimeout:            "10m",
+		MetadataMaxStaleness:     "168h",
+		PrefetchParallelism:      4,
+		HashlessItems:            "reject",
+		CacheMaxAge:              "120h",
+		ServiceMode:              false,
+		ServiceCommand:           "",
+		ServiceInstall:           false,
+		ServiceRemove:            false,
+		ServiceStart:             false,
+		ServiceStop:              false,
+		ServiceStatus:            false,
+		ServiceName:              "gorilla",
+		ServiceInterval:          "1h",
+		ServicePipeName:          "gorilla-service",
+		ConfigPath:               "testdata/test_config.yaml",
 	}
 
 	// Save the original arguments
@@ -183,6 +202,9 @@ func Example() {
 	// -C, -checkonly	    enable check only mode
 	// -b, -build          build catalog files from package-info files
 	// -i, -import         create a package-info file from an installer package
+	// -plan               show what a run would change without making any changes
+	// -planfile           write the plan as a JSON document to this path (implies -plan)
+	// -report             list saved run reports, or print one (list|N, where 1 is the most recent)
 	// -v, -verbose        enable verbose output
 	// -d, -debug          enable debug output
 	// -a, -about          displays the version number and other build info
@@ -194,5 +216,92 @@ func Example() {
 	// -servicestart       start Gorilla Windows service
 	// -servicestop        stop Gorilla Windows service
 	// -servicestatus      show Gorilla Windows service status
+	// -protectsecret      read a secret from stdin and print a dpapi: reference to use in the configuration file
 	// -h, -help           display this help message
 }
+
+func TestGetReportModeWithoutManifestOrURL(t *testing.T) {
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+	origReportArg := reportArg
+	defer func() { reportArg = origReportArg }()
+	origExit := osExit
+	defer func() { osExit = origExit }()
+	osExit = func(code int) {
+		t.Fatalf("unexpected exit with code %d", code)
+	}
+
+	// -1 turns the report history off
+	for _, history := range []int{3, -1} {
+		configPath := filepath.Join(t.TempDir(), "report_config.yaml")
+		configYAML := []byte(fmt.Sprintf(`
+app_data_path: c:/cpe/gorilla/
+report_history: %d
+`, history))
+		if err := os.WriteFile(configPath, configYAML, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Flag parsing is process-global in this package; set mode flags directly for test stability.
+		reportArg = "list"
+		os.Args = []string{"gorilla.exe", "--config", configPath}
+		cfg := Get()
+
+		if cfg.ReportArg != "list" {
+			t.Fatalf("unexpected ReportArg: %s", cfg.ReportArg)
+		}
+		if cfg.ReportHistory != history {
+			t.Fatalf("unexpected ReportHistory: %d", cfg.ReportHistory)
+		}
+	}
+}
+
+// TestParseSize verifies sizes with and without units
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":  1024,
+		"500MB": 500 << 20,
+		"1.5GB": 3 << 29,
+		"10 gb": 10 << 30,
+		"2TB":   2 << 40,
+		"64KB":  64 << 10,
+		"100B":  100,
+		"0":     0,
+	}
+	for value, want := range tests {
+		have, err := ParseSize(value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", value, err)
+			continue
+		}
+		if have != want {
+			t.Errorf("%s: have %d, want %d", value, have, want)
+		}
+	}
+
+	for _, value := range []string{"", "GB", "-1GB", "ten"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+// TestParseHours verifies ranges of local times
+func TestParseHours(t *testing.T) {
+	start, end, err := ParseHours("18:00-07:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := start, 18*time.Hour; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := end, 7*time.Hour+30*time.Minute; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+
+	for _, value := range []string{"18:00", "6pm-7am", "25:00-07:00"} {
+		if _, _, err := ParseHours(value); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
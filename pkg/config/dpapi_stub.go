@@ -0,0 +1,16 @@
+//go:build !windows
+
+package config
+
+import "errors"
+
+// errNoDPAPI is returned when a protected secret is used on a platform without DPAPI
+var errNoDPAPI = errors.New("DPAPI protected secrets are only supported on Windows")
+
+func dpapiProtect(data []byte) ([]byte, error) {
+	return nil, errNoDPAPI
+}
+
+func dpapiUnprotect(data []byte) ([]byte, error) {
+	return nil, errNoDPAPI
+}
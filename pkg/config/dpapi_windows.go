@@ -0,0 +1,39 @@
+//go:build windows
+
+package config
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// dpapiProtect encrypts data with DPAPI. The machine key is used so the Gorilla service,
+// running as SYSTEM, can decrypt secrets protected by an administrator.
+func dpapiProtect(data []byte) ([]byte, error) {
+	return dpapiCall(data, func(in, out *windows.DataBlob) error {
+		return windows.CryptProtectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_LOCAL_MACHINE|windows.CRYPTPROTECT_UI_FORBIDDEN, out)
+	})
+}
+
+// dpapiUnprotect decrypts data encrypted by `dpapiProtect`
+func dpapiUnprotect(data []byte) ([]byte, error) {
+	return dpapiCall(data, func(in, out *windows.DataBlob) error {
+		return windows.CryptUnprotectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, out)
+	})
+}
+
+// dpapiCall passes data to a DPAPI function and copies the result before freeing it
+func dpapiCall(data []byte, call func(in, out *windows.DataBlob) error) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	in := windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+	var out windows.DataBlob
+	if err := call(&in, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Prefixes of configuration values that refer to a secret stored somewhere else
+const (
+	envPrefix   = "env:"
+	filePrefix  = "file:"
+	dpapiPrefix = "dpapi:"
+)
+
+// ResolveSecret returns the secret a configuration value refers to.
+// "env:NAME" reads an environment variable, "file:/path" reads a file, and "dpapi:<base64>" decrypts a
+// blob made by `-protectsecret` on Windows. Any other value is returned as is.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envPrefix):
+		name := strings.TrimPrefix(value, envPrefix)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, filePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, filePrefix))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, dpapiPrefix):
+		blob, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, dpapiPrefix))
+		if err != nil {
+			return "", fmt.Errorf("unable to decode protected secret: %w", err)
+		}
+		secret, err := dpapiUnprotect(blob)
+		if err != nil {
+			return "", fmt.Errorf("unable to decrypt protected secret: %w", err)
+		}
+		return string(secret), nil
+	}
+	return value, nil
+}
+
+// resolveSecrets replaces each credential in `cfg` that refers to a secret with the secret itself
+func resolveSecrets(cfg *Configuration) error {
+	var errs []error
+	for name, value := range map[string]*string{
+		"auth_user":            &cfg.AuthUser,
+		"auth_pass":            &cfg.AuthPass,
+		"auth_token":           &cfg.AuthToken,
+		"oauth_client_id":      &cfg.OAuthClientID,
+		"oauth_client_secret":  &cfg.OAuthClientSecret,
+		"proxy_user":           &cfg.ProxyUser,
+		"proxy_pass":           &cfg.ProxyPass,
+		"s3_access_key_id":     &cfg.S3AccessKeyID,
+		"s3_secret_access_key": &cfg.S3SecretAccessKey,
+		"s3_session_token":     &cfg.S3SessionToken,
+	} {
+		secret, err := ResolveSecret(*value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		*value = secret
+	}
+	for header, value := range cfg.AuthHeaders {
+		secret, err := ResolveSecret(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("auth_headers %s: %w", header, err))
+			continue
+		}
+		cfg.AuthHeaders[header] = secret
+	}
+	return errors.Join(errs...)
+}
+
+// protectSecret reads a secret from the first line of `input` and returns a "dpapi:" reference to it
+// that can be used in the configuration file
+func protectSecret(input io.Reader) (string, error) {
+	line, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		return "", errors.New("no secret provided")
+	}
+	blob, err := dpapiProtect([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("unable to protect secret: %w", err)
+	}
+	return dpapiPrefix + base64.StdEncoding.EncodeToString(blob), nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestResolveSecret verifies each kind of secret reference
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("GORILLA_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secretFile, []byte("from-file\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "pizza", want: "pizza"},
+		{value: "", want: ""},
+		{value: "env:GORILLA_TEST_SECRET", want: "from-env"},
+		{value: "env:GORILLA_TEST_MISSING", wantErr: true},
+		{value: "file:" + secretFile, want: "from-file"},
+		{value: "file:" + secretFile + ".missing", wantErr: true},
+		{value: "dpapi:not base64", wantErr: true},
+	}
+	for _, tt := range tests {
+		have, err := ResolveSecret(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: have error %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if have != tt.want {
+			t.Errorf("%s: have %q, want %q", tt.value, have, tt.want)
+		}
+	}
+}
+
+// TestGetResolvesSecrets verifies references in the config file are resolved when it is loaded
+func TestGetResolvesSecrets(t *testing.T) {
+	t.Setenv("GORILLA_TEST_PASS", "pizza")
+	tokenFile := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "secret_config.yaml")
+	configYAML := []byte(`
+url: https://example.com/gorilla/
+manifest: example_manifest
+app_data_path: c:/cpe/gorilla/
+auth_user: johnny
+auth_pass: env:GORILLA_TEST_PASS
+auth_headers:
+  X-Api-Key: file:` + filepath.ToSlash(tokenFile) + `
+`)
+	if err := os.WriteFile(configPath, configYAML, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+	os.Args = []string{"gorilla.exe", "-config", configPath}
+	cfg := Get()
+
+	if have, want := cfg.AuthUser, "johnny"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := cfg.AuthPass, "pizza"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := cfg.AuthHeaders["X-Api-Key"], "s3cr3t"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
+
+// TestProtectSecret verifies a protected secret can be resolved again
+func TestProtectSecret(t *testing.T) {
+	reference, err := protectSecret(strings.NewReader("pizza\r\n"))
+	if runtime.GOOS != "windows" {
+		if err == nil {
+			t.Errorf("Expected protecting a secret to fail without DPAPI")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reference, dpapiPrefix) {
+		t.Fatalf("Expected a dpapi reference, got %s", reference)
+	}
+	secret, err := ResolveSecret(reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := secret, "pizza"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
@@ -0,0 +1,187 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before it expires an access token is replaced
+const tokenRefreshMargin = 30 * time.Second
+
+// accessToken is a token returned by an OAuth2 token endpoint
+type accessToken struct {
+	key    string // the settings the token was requested with
+	value  string
+	expiry time.Time // zero if the endpoint did not say when it expires
+}
+
+var (
+	// oauthToken is reused by every request until it expires
+	oauthToken   accessToken
+	oauthTokenMu sync.Mutex
+)
+
+// authorize adds the configured authentication to a request.
+// A bearer token from OAuth2, a token file, or `auth_token` is used in that order, otherwise basic auth.
+// Custom headers are added last, so they can replace any of these.
+// Credentials are only sent to the repo, never to package sources or other hosts.
+func authorize(req *http.Request) error {
+	if !trustedHost(req.URL) {
+		return nil
+	}
+
+	token, err := bearerToken(req.Context())
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if downloadCfg.AuthUser != "" && downloadCfg.AuthPass != "" {
+		req.SetBasicAuth(downloadCfg.AuthUser, downloadCfg.AuthPass)
+	}
+
+	for name, value := range downloadCfg.AuthHeaders {
+		req.Header.Set(name, value)
+	}
+	return nil
+}
+
+// trustedHost returns true if `target` is on the same host as `url`, `url_packages` or `report_url`
+func trustedHost(target *url.URL) bool {
+	for _, trusted := range []string{downloadCfg.URL, downloadCfg.URLPackages, downloadCfg.ReportURL} {
+		if trusted == "" {
+			continue
+		}
+		trustedURL, err := url.Parse(trusted)
+		if err != nil {
+			continue
+		}
+		if trustedURL.Host != "" && strings.EqualFold(trustedURL.Host, target.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the configured bearer token, or an empty string if there is none
+func bearerToken(ctx context.Context) (string, error) {
+	if downloadCfg.OAuthTokenURL != "" {
+		return fetchToken(ctx)
+	}
+	if downloadCfg.AuthTokenFile != "" {
+		// Read the file every time, so a rotated token is picked up without a restart
+		data, err := os.ReadFile(downloadCfg.AuthTokenFile)
+		if err != nil {
+			return "", &permanentError{fmt.Errorf("unable to read auth token file: %w", err)}
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return downloadCfg.AuthToken, nil
+}
+
+// fetchToken returns an access token from the OAuth2 token endpoint using the client credentials grant.
+// The token is cached until shortly before it expires.
+func fetchToken(ctx context.Context) (string, error) {
+	key := strings.Join([]string{
+		downloadCfg.OAuthTokenURL,
+		downloadCfg.OAuthClientID,
+		downloadCfg.OAuthClientSecret,
+		strings.Join(downloadCfg.OAuthScopes, " "),
+	}, "\n")
+
+	oauthTokenMu.Lock()
+	defer oauthTokenMu.Unlock()
+	if oauthToken.key == key && oauthToken.value != "" &&
+		(oauthToken.expiry.IsZero() || time.Now().Add(tokenRefreshMargin).Before(oauthToken.expiry)) {
+		return oauthToken.value, nil
+	}
+
+	client, err := httpClient()
+	if err != nil {
+		return "", &permanentError{err}
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(downloadCfg.OAuthScopes) > 0 {
+		form.Set("scope", strings.Join(downloadCfg.OAuthScopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", downloadCfg.OAuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(downloadCfg.OAuthClientID), url.QueryEscape(downloadCfg.OAuthClientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to get access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get access token: %w", &StatusError{URL: downloadCfg.OAuthTokenURL, StatusCode: resp.StatusCode})
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("unable to parse access token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", &permanentError{fmt.Errorf("%s : No access token in response", downloadCfg.OAuthTokenURL)}
+	}
+
+	oauthToken = accessToken{key: key, value: body.AccessToken}
+	if body.ExpiresIn > 0 {
+		oauthToken.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return oauthToken.value, nil
+}
+
+// resetToken forgets the cached access token, so the next request gets a new one.
+// It is called when a server rejects the token before it was expected to expire.
+func resetToken() {
+	oauthTokenMu.Lock()
+	defer oauthTokenMu.Unlock()
+	oauthToken = accessToken{}
+}
+
+// doRequest sends a request to the server. If the server rejects the access token before it was expected
+// to expire, the token is replaced and the request is sent once more.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || downloadCfg.OAuthTokenURL == "" || !trustedHost(req.URL) {
+		return resp, err
+	}
+	// A request body that can not be read again is not retried
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	resetToken()
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := authorize(retry); err != nil {
+		return nil, err
+	}
+	return client.Do(retry)
+}
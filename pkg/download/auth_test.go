@@ -0,0 +1,174 @@
+package download
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// gateway returns a handler that only serves the test file when the request has the header `want`
+func gateway(name, want string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(name) != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		serveTestFile(w, r)
+	}
+}
+
+// TestGetBearerToken verifies a static token and a token file are sent as a bearer token
+func TestGetBearerToken(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	ts := httptest.NewServer(gateway("Authorization", "Bearer s3cr3t"))
+	defer ts.Close()
+
+	// A static token takes the place of basic auth
+	downloadCfg = config.Configuration{URL: ts.URL + "/", AuthUser: "johnny", AuthPass: "pizza", AuthToken: "s3cr3t"}
+	if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+		t.Errorf("Expected the static token to be accepted: %v", err)
+	}
+
+	// The token file is read on every request
+	tokenFile := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(tokenFile, []byte("expired\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	downloadCfg = config.Configuration{URL: ts.URL + "/", AuthTokenFile: tokenFile}
+	if _, err := Get(ts.URL + "/hashtest.txt"); err == nil {
+		t.Errorf("Expected the old token to be rejected")
+	}
+	if err := os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+		t.Errorf("Expected the new token to be accepted: %v", err)
+	}
+}
+
+// TestGetAuthHeaders verifies custom headers are sent with each request
+func TestGetAuthHeaders(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	ts := httptest.NewServer(gateway("X-Api-Key", "0123456789abcdef"))
+	defer ts.Close()
+
+	downloadCfg = config.Configuration{URL: ts.URL + "/", AuthHeaders: map[string]string{"X-Api-Key": "0123456789abcdef"}}
+	if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+		t.Errorf("Expected the custom header to be accepted: %v", err)
+	}
+}
+
+// TestGetOAuth verifies access tokens are fetched with the client credentials grant, cached, and refreshed
+func TestGetOAuth(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	defer resetToken()
+
+	issued := 0
+	current := ""
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "gorilla" || secret != "hunter2" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if have, want := r.FormValue("scope"), "repo.read"; have != want {
+			t.Errorf("have scope %q, want %q", have, want)
+		}
+		issued++
+		current = "token-" + strconv.Itoa(issued)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": current, "token_type": "Bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		gateway("Authorization", "Bearer "+current)(w, r)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	downloadCfg = config.Configuration{
+		URL:               ts.URL + "/",
+		OAuthTokenURL:     ts.URL + "/token",
+		OAuthClientID:     "gorilla",
+		OAuthClientSecret: "hunter2",
+		OAuthScopes:       []string{"repo.read"},
+	}
+
+	// The token is reused until it expires
+	for range 2 {
+		if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+			t.Fatalf("Expected the access token to be accepted: %v", err)
+		}
+	}
+	if have, want := issued, 1; have != want {
+		t.Errorf("have %d tokens issued, want %d", have, want)
+	}
+
+	// A token the server no longer accepts is replaced, and the request is sent again
+	current = "revoked"
+	if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+		t.Errorf("Expected the request to be retried with a new access token: %v", err)
+	}
+	if have, want := issued, 2; have != want {
+		t.Errorf("have %d tokens issued, want %d", have, want)
+	}
+
+	// Bad client credentials are reported
+	resetToken()
+	downloadCfg.OAuthClientSecret = "wrong"
+	if _, err := Get(ts.URL + "/hashtest.txt"); err == nil {
+		t.Errorf("Expected the token request to fail with the wrong secret")
+	}
+}
+
+// TestPostOAuthRetry verifies an upload rejected with a revoked token is sent again with its body
+func TestPostOAuthRetry(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	defer resetToken()
+
+	issued := 0
+	var bodies []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issued++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "token-" + strconv.Itoa(issued)})
+	})
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		// Only the second token is accepted
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	downloadCfg = config.Configuration{
+		ReportURL:     ts.URL + "/reports",
+		OAuthTokenURL: ts.URL + "/token",
+	}
+	if err := Post(ts.URL+"/reports", "application/json", []byte(`{"ok":true}`)); err != nil {
+		t.Fatalf("Expected the upload to be retried with a new access token: %v", err)
+	}
+
+	expected := []string{`{"ok":true}`, `{"ok":true}`}
+	if !reflect.DeepEqual(expected, bodies) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, bodies)
+	}
+}
@@ -0,0 +1,172 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+)
+
+// MetadataDir is the directory under the cache path that stores copies of manifests and catalogs
+const MetadataDir = "metadata"
+
+// cacheEntry describes a cached copy of a url
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// cachePaths returns the location of the cached body and its metadata for a url
+func cachePaths(url string) (body string, meta string) {
+	sum := sha256.Sum256([]byte(url))
+	base := filepath.Join(downloadCfg.CachePath, MetadataDir, hex.EncodeToString(sum[:]))
+	return base, base + ".json"
+}
+
+// readCache returns the cached copy of a url, if we have one
+func readCache(url string) ([]byte, cacheEntry, bool) {
+	bodyPath, metaPath := cachePaths(url)
+	var entry cacheEntry
+	metaJSON, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, entry, false
+	}
+	if err := json.Unmarshal(metaJSON, &entry); err != nil || entry.URL != url {
+		return nil, entry, false
+	}
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return nil, entry, false
+	}
+	return body, entry, true
+}
+
+// writeCache saves a copy of a url to disk
+func writeCache(url string, body []byte, entry cacheEntry) error {
+	bodyPath, metaPath := cachePaths(url)
+	if err := os.MkdirAll(filepath.Dir(bodyPath), 0755); err != nil {
+		return err
+	}
+	metaJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		if err := os.WriteFile(bodyPath, body, 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(metaPath, metaJSON, 0644)
+}
+
+// Cached downloads a url like `Get`, but keeps a copy under the cache path.
+// When we already have a copy, the server is asked to send the url only if it has changed.
+// If the server can not be reached, the cached copy is returned as long as it was last
+// fetched within the configured maximum staleness.
+func Cached(url string) ([]byte, error) {
+	// Without a cache path there is nowhere to keep a copy
+	if downloadCfg.CachePath == "" {
+		return Get(url)
+	}
+
+	cachedBody, entry, haveCache := readCache(url)
+	if !haveCache {
+		entry = cacheEntry{URL: url}
+	}
+
+	var responseBody []byte
+	var notModified bool
+	err := withRetries(url, parseDuration(downloadCfg.MetadataTimeout), func(ctx context.Context) error {
+		var err error
+		responseBody, notModified, err = getConditional(ctx, url, &entry, haveCache)
+		return err
+	})
+	if err == nil {
+		// Only the metadata needs to be saved if the body has not changed
+		entry.FetchedAt = time.Now().UTC()
+		if writeErr := writeCache(url, responseBody, entry); writeErr != nil {
+			gorillalog.Warn("Unable to cache", url, writeErr)
+		}
+		if notModified {
+			gorillalog.Debug("Using cached copy, not modified on server:", url)
+			return cachedBody, nil
+		}
+		return responseBody, nil
+	}
+
+	// The server answered, so the copy we have is not what it wants us to use
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode < 500 {
+		return nil, err
+	}
+
+	// Fall back to the last good copy if it is recent enough
+	if haveCache {
+		age := time.Since(entry.FetchedAt)
+		if maxStaleness := parseDuration(downloadCfg.MetadataMaxStaleness); age <= maxStaleness {
+			gorillalog.Warn("Unable to retrieve", url, err)
+			gorillalog.Warn("Using cached copy from", entry.FetchedAt.Local().Format(time.RFC1123))
+			return cachedBody, nil
+		}
+		gorillalog.Warn("Cached copy of", url, "is too old to use:", age.Round(time.Second))
+	}
+	return nil, err
+}
+
+// getConditional makes a single attempt to download a url. If `conditional` is true, the validators
+// in `entry` are sent and true is returned if the server reports the url has not been modified.
+// Any new validators are saved to `entry`.
+func getConditional(ctx context.Context, url string, entry *cacheEntry, conditional bool) ([]byte, bool, error) {
+	client, err := httpClient()
+	if err != nil {
+		return nil, false, &permanentError{err}
+	}
+
+	req, err := newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		gorillalog.Warn("Unable to request url:", url, err)
+		return nil, false, err
+	}
+	if conditional {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case conditional && resp.StatusCode == http.StatusNotModified:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, true, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s : Unable to read response: %w", url, err)
+	}
+
+	entry.ETag = resp.Header.Get("ETag")
+	entry.LastModified = resp.Header.Get("Last-Modified")
+	return responseBody, false, nil
+}
@@ -0,0 +1,113 @@
+package download
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// cacheConfig returns a configuration with a temporary cache path
+func cacheConfig(t *testing.T) config.Configuration {
+	return config.Configuration{
+		CachePath:            t.TempDir(),
+		MetadataMaxStaleness: "1h",
+	}
+}
+
+// TestCachedConditional verifies that a cached copy is revalidated with the server
+func TestCachedConditional(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = cacheConfig(t)
+
+	var conditions []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditions = append(conditions, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("managed_installs: []"))
+	}))
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		body, err := Cached(ts.URL + "/manifests/example.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := string(body), "managed_installs: []"; have != want {
+			t.Errorf("request %d: have %q, want %q", i+1, have, want)
+		}
+	}
+
+	if have, want := len(conditions), 2; have != want {
+		t.Fatalf("have %d requests, want %d", have, want)
+	}
+	if have, want := conditions[1], `"v1"`; have != want {
+		t.Errorf("have If-None-Match %q, want %q", have, want)
+	}
+}
+
+// TestCachedFallback verifies the cached copy is used when the server is unreachable
+func TestCachedFallback(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = cacheConfig(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("example: catalog"))
+	}))
+	catalogURL := ts.URL + "/catalogs/example.yaml"
+	if _, err := Cached(catalogURL); err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+
+	// The server is gone, so we should get the last good copy
+	body, err := Cached(catalogURL)
+	if err != nil {
+		t.Fatalf("Expected the cached copy, got: %v", err)
+	}
+	if have, want := string(body), "example: catalog"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+
+	// Unless it is too old
+	downloadCfg.MetadataMaxStaleness = "1ns"
+	if _, err := Cached(catalogURL); err == nil {
+		t.Errorf("Expected an error when the cached copy is too old")
+	}
+}
+
+// TestCachedNotFound verifies the cached copy is not used when the server says the url is gone
+func TestCachedNotFound(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = cacheConfig(t)
+
+	found := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("managed_installs: []"))
+	}))
+	defer ts.Close()
+
+	if _, err := Cached(ts.URL + "/manifests/example.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	found = false
+	_, err := Cached(ts.URL + "/manifests/example.yaml")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 status error, got: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package download
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// client is shared by every request so connections are kept alive between downloads
+	client    *http.Client
+	clientKey clientSettings
+	clientMu  sync.Mutex
+)
+
+// fileStamp identifies a version of a file on disk
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// clientSettings contains everything the shared client was built from.
+// When any of it changes, the client is rebuilt.
+type clientSettings struct {
+	tlsAuth    bool
+	clientCert string
+	clientKey  string
+	serverCert string
+	stamps     [3]fileStamp
+	proxyURL   string
+	noProxy    string
+	proxyUser  string
+	proxyPass  string
+}
+
+// stamp returns the modification time and size of a file, or a zero value if it can not be read
+func stamp(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// currentSettings returns the client settings for the current config and certificate files
+func currentSettings() clientSettings {
+	settings := clientSettings{
+		tlsAuth:   downloadCfg.TLSAuth,
+		proxyURL:  downloadCfg.ProxyURL,
+		noProxy:   strings.Join(downloadCfg.NoProxy, ","),
+		proxyUser: downloadCfg.ProxyUser,
+		proxyPass: downloadCfg.ProxyPass,
+	}
+	if settings.tlsAuth {
+		settings.clientCert = downloadCfg.TLSClientCert
+		settings.clientKey = downloadCfg.TLSClientKey
+		settings.serverCert = downloadCfg.TLSServerCert
+		settings.stamps = [3]fileStamp{
+			stamp(settings.clientCert),
+			stamp(settings.clientKey),
+			stamp(settings.serverCert),
+		}
+	}
+	return settings
+}
+
+// httpClient returns the shared http client, building it the first time it is needed.
+// If the config or any of the TLS certificate files have changed, a new client is built,
+// so calling `SetConfig` at the start of every run keeps the existing connections.
+func httpClient() (*http.Client, error) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	settings := currentSettings()
+	if client != nil && settings == clientKey {
+		return client, nil
+	}
+
+	newClient, err := buildClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Let go of any connections made with the previous settings
+	if client != nil {
+		client.CloseIdleConnections()
+	}
+	client = newClient
+	clientKey = settings
+	return client, nil
+}
+
+// buildClient returns an http client configured with our timeouts and, if enabled, TLS auth
+func buildClient() (*http.Client, error) {
+	proxy, err := proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	// Defining the transport separately so we can add a `file://` protocol
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	// If TLSAuth is true, configure server and client certs
+	if downloadCfg.TLSAuth {
+		// Load	the client certificate and private key
+		clientCert, err := tls.LoadX509KeyPair(downloadCfg.TLSClientCert, downloadCfg.TLSClientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		// Load server certificates
+		serverCert, err := os.ReadFile(downloadCfg.TLSServerCert)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(serverCert)
+
+		// Setup the tls configuration
+		transport.TLSClientConfig = &tls.Config{
+			Certificates: []tls.Certificate{clientCert},
+			RootCAs:      caCertPool,
+			// Insecure, but might need to be an option for odd configurations in the future
+			// Renegotiation: tls.RenegotiateFreelyAsClient,
+		}
+	}
+
+	// Register a file handler so `file://` works, including for package sources on a file share
+	transport.RegisterProtocol("file", fileTransport{})
+	transport.RegisterProtocol("s3", s3Transport{base: transport})
+
+	// Create the client using our custom transport
+	return &http.Client{Transport: transport}, nil
+}
+
+// fileTransport serves `file://` urls from the local disk, or from a file share when the url has a host
+type fileTransport struct{}
+
+// RoundTrip implements http.RoundTripper
+func (fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.NewFileTransport(http.Dir(fileRoot(req.URL.Host))).RoundTrip(req)
+}
+
+// fileRoot returns the directory a `file://` url is relative to.
+// A url like file://server/share/package.msi refers to \\server\share\package.msi
+func fileRoot(host string) string {
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return "/"
+	}
+	return `\\` + host
+}
@@ -0,0 +1,96 @@
+package download
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// TestHTTPClientReusesConnections verifies that repeated downloads share a connection
+func TestHTTPClientReusesConnections(t *testing.T) {
+	origCfg := downloadCfg
+	defer SetConfig(origCfg)
+	SetConfig(config.Configuration{})
+
+	var connections atomic.Int32
+	ts := httptest.NewUnstartedServer(router())
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if have, want := connections.Load(), int32(1); have != want {
+		t.Errorf("have %d connections, want %d", have, want)
+	}
+}
+
+// TestHTTPClientRebuilds verifies the client is rebuilt when the config or certificates change
+func TestHTTPClientRebuilds(t *testing.T) {
+	origCfg := downloadCfg
+	defer SetConfig(origCfg)
+
+	// Copy the certificates so we can change them
+	dir := t.TempDir()
+	for _, name := range []string{"client.pem", "client.key", "server.pem"} {
+		if err := copy(filepath.Join("testdata", name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetConfig(config.Configuration{
+		TLSAuth:       true,
+		TLSClientCert: filepath.Join(dir, "client.pem"),
+		TLSClientKey:  filepath.Join(dir, "client.key"),
+		TLSServerCert: filepath.Join(dir, "server.pem"),
+	})
+
+	first, err := httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("Expected the client to be reused when nothing changed")
+	}
+
+	// Replacing the client certificate should build a new client
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "client.pem"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	third, err := httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == second {
+		t.Errorf("Expected a new client after the certificate changed")
+	}
+
+	// So should a new config
+	SetConfig(config.Configuration{})
+	fourth, err := httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fourth == third {
+		t.Errorf("Expected a new client after the config changed")
+	}
+}
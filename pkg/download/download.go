@@ -1,17 +1,19 @@
 package download
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"net"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -35,113 +37,276 @@ func File(file string, url string) error {
 	_, fileName := path.Split(url)
 	absPath := filepath.Join(file, fileName)
 
+	return stream(absPath, url, nil)
+}
+
+// StatusError is returned when a server responds with an unexpected status code
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s : Download status code: %d", e.URL, e.StatusCode)
+}
+
+// permanentError wraps an error that another attempt will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// maxBackoff caps the delay between attempts
+const maxBackoff = time.Minute
+
+// parseDuration returns the duration of a config value; `config.Get` has already validated it
+func parseDuration(value string) time.Duration {
+	duration, _ := time.ParseDuration(value)
+	return duration
+}
+
+// retryable returns true if another attempt might succeed after `err`
+func retryable(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(downloadCfg.DownloadRetryStatusCodes, statusErr.StatusCode)
+	}
+	// Anything else is a network error, which is worth trying again
+	return true
+}
+
+// backoff returns how long to wait before retry number `retry`.
+// The configured backoff doubles with each retry, plus a random jitter.
+func backoff(retry int) time.Duration {
+	delay := parseDuration(downloadCfg.DownloadBackoff)
+	for i := 1; i < retry && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+	if jitter := parseDuration(downloadCfg.DownloadJitter); jitter > 0 {
+		delay += rand.N(jitter)
+	}
+	return delay
+}
+
+// withRetries runs `attempt` until it succeeds, fails with an error that can not be retried,
+// runs out of retries, or the overall `timeout` passes. A timeout of 0 means no limit.
+func withRetries(url string, timeout time.Duration, attempt func(ctx context.Context) error) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	attempts := max(downloadCfg.DownloadRetries, 0) + 1
+	var err error
+	for i := 1; i <= attempts; i++ {
+		gorillalog.Debug("Download attempt", i, "of", attempts, url)
+		err = attempt(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s : Download timeout after %s: %w", url, timeout, err)
+		}
+		if !retryable(err) || i == attempts {
+			break
+		}
+
+		delay := backoff(i)
+		gorillalog.Warn(fmt.Sprintf("Download attempt %d of %d failed, retrying in %s:", i, attempts, delay), err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s : Download timeout after %s: %w", url, timeout, err)
+		case <-time.After(delay):
+		}
+	}
+	return err
+}
+
+// partialSuffix is added to a file while it is being downloaded
+const partialSuffix = ".partial"
+
+// stream downloads a url directly to disk, hashing the content as it is written.
+// The download is kept in a `.partial` file until it is complete (and, if `expected` is not nil,
+// until the hash matches), then it is renamed to `absFile`. If an earlier download was
+// interrupted, the `.partial` file is resumed with a Range request.
+func stream(absFile, url string, expected *expectedHash) error {
 	// Create the directory
-	err := os.MkdirAll(filepath.Clean(file), 0755)
+	err := os.MkdirAll(filepath.Dir(absFile), 0755)
 	if err != nil {
-		gorillalog.Warn("Unable to make filepath:", file, err)
+		gorillalog.Warn("Unable to make filepath:", filepath.Dir(absFile), err)
 	}
 
-	// Create the file
-	f, err := os.Create(filepath.Clean(absPath))
+	partialFile := absFile + partialSuffix
+	err = withRetries(url, parseDuration(downloadCfg.PackageTimeout), func(ctx context.Context) error {
+		resumed, err := streamPartial(ctx, partialFile, url, expected)
+
+		// A resumed download may have started from a stale partial file, so try once more from scratch
+		if err != nil && resumed {
+			gorillalog.Warn("Resumed download failed, restarting:", url, err)
+			if removeErr := os.Remove(partialFile); removeErr != nil && !os.IsNotExist(removeErr) {
+				return &permanentError{removeErr}
+			}
+			_, err = streamPartial(ctx, partialFile, url, expected)
+		}
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	// get the content at the provided url
-	responseBody, err := Get(url)
+	// The download is complete, move it into place
+	return os.Rename(partialFile, absFile)
+}
+
+// streamPartial downloads a url into `partialFile`, resuming from its current size when possible.
+// It returns true if existing content was resumed.
+func streamPartial(ctx context.Context, partialFile, url string, expected *expectedHash) (bool, error) {
+	// Determine how much we already have
+	var offset int64
+	if info, err := os.Stat(partialFile); err == nil {
+		offset = info.Size()
+	}
+
+	client, err := httpClient()
 	if err != nil {
-		return err
+		return false, &permanentError{err}
 	}
 
-	// Write the responseBody to the file we opened
-	_, err = f.Write(responseBody)
+	req, err := newRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		gorillalog.Warn("Unable to request url:", url, err)
+		return false, err
+	}
+	if offset > 0 {
+		gorillalog.Debug("Resuming download at byte", offset, url)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
 
-	return nil
-}
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
 
-// Get downloads a url and returns the body
-// Timeout is 10 seconds
-// Will only write to disk if http status code is 2XX
-func Get(url string) ([]byte, error) {
+	// Decide if we are appending to the partial file or starting over
+	resumed := false
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored our range, or we did not send one
+	case http.StatusPartialContent:
+		var start int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+			return true, fmt.Errorf("%s : Unexpected content range: %q", url, resp.Header.Get("Content-Range"))
+		}
+		resumed = true
+		flags = os.O_WRONLY | os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not a prefix of what the server has
+		return true, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	default:
+		return false, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
 
-	// Declare the http client
-	var client *http.Client
+	f, err := os.OpenFile(partialFile, flags, 0644)
+	if err != nil {
+		return resumed, &permanentError{err}
+	}
+	defer f.Close()
 
-	// If TLSAuth is true, configure server and client certs
-	if downloadCfg.TLSAuth {
-		// Load	the client certificate and private key
-		clientCert, err := tls.LoadX509KeyPair(downloadCfg.TLSClientCert, downloadCfg.TLSClientKey)
+	// Hash anything we already have before appending to it
+	h := sha256.New()
+	if expected != nil {
+		h = expected.newHash()
+	}
+	if resumed {
+		existing, err := os.Open(partialFile)
 		if err != nil {
-			return nil, err
+			return resumed, err
 		}
-
-		// Load server certificates
-		serverCert, err := os.ReadFile(downloadCfg.TLSServerCert)
+		_, err = io.Copy(h, existing)
+		existing.Close()
 		if err != nil {
-			return nil, err
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(serverCert)
-
-		// Setup the tls configuration
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{clientCert},
-			RootCAs:      caCertPool,
-			// Insecure, but might need to be an option for odd configurations in the future
-			// Renegotiation: tls.RenegotiateFreelyAsClient,
+			return resumed, err
 		}
+	}
 
-		// Setup the http client
-		client = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: tlsConfig,
-				Dial: (&net.Dialer{
-					Timeout:   10 * time.Second,
-					KeepAlive: 10 * time.Second,
-				}).Dial,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ResponseHeaderTimeout: 10 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-			},
-		}
-	} else {
-		// Setup our http client without tls auth
-		// Defining the transport separately so we can add a `file://` protocol
-		transport := &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   10 * time.Second,
-				KeepAlive: 10 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ResponseHeaderTimeout: 10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
+	// Write the body to disk and hash it at the same time
+	if _, err := io.Copy(io.MultiWriter(f, h), throttle(ctx, resp.Body)); err != nil {
+		// Leave the partial file in place so the next attempt can resume
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return resumed, err
+	}
+
+	if expected != nil && !expected.matches(h) {
+		if err := os.Remove(partialFile); err != nil {
+			gorillalog.Warn("Unable to remove invalid download:", partialFile, err)
 		}
+		return resumed, &permanentError{fmt.Errorf("%s : Downloaded file %s hash %s does not match expected %s",
+			url, expected.algorithm, hex.EncodeToString(h.Sum(nil)), expected.digest)}
+	}
+
+	return resumed, nil
+}
 
-		// Register a file handler so `file://` works
-		transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+// newRequest builds a request and adds any configured authentication.
+// Errors that another attempt will not fix are returned as a `permanentError`.
+func newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, &permanentError{err}
+	}
 
-		// Create the client using our custom transport
-		client = &http.Client{Transport: transport}
+	if err := authorize(req); err != nil {
+		return nil, err
 	}
 
-	// Build the request
-	req, err := http.NewRequest("GET", url, nil)
+	return req, nil
+}
+
+// Get downloads a url and returns the body
+// Failed attempts are retried according to the configured retry policy, within the metadata timeout
+// Will only return the body if http status code is 200
+func Get(url string) ([]byte, error) {
+	var responseBody []byte
+	err := withRetries(url, parseDuration(downloadCfg.MetadataTimeout), func(ctx context.Context) error {
+		var err error
+		responseBody, err = get(ctx, url)
+		return err
+	})
+	return responseBody, err
+}
+
+// get makes a single attempt to download a url and returns the body
+func get(ctx context.Context, url string) ([]byte, error) {
+
+	// Declare the http client
+	client, err := httpClient()
 	if err != nil {
-		gorillalog.Warn("Unable to request url:", url, err)
+		return nil, &permanentError{err}
 	}
 
-	// If we have a user and pass, configure basic auth
-	if downloadCfg.AuthUser != "" && downloadCfg.AuthPass != "" {
-		req.SetBasicAuth(downloadCfg.AuthUser, downloadCfg.AuthPass)
+	// Build the request
+	req, err := newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		gorillalog.Warn("Unable to request url:", url, err)
+		return nil, err
 	}
 
 	// Actually send the request, using the client we setup
 	// Storing the response in resp
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 
 	if err != nil {
 		return nil, err
@@ -150,7 +315,7 @@ func Get(url string) ([]byte, error) {
 
 	// Check that the request was successful
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("%s : Download status code: %d", url, resp.StatusCode)
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode}
 	}
 
 	// Copy the download to a a buffer
@@ -162,21 +327,64 @@ func Get(url string) ([]byte, error) {
 	return responseBody, nil
 }
 
-// Verify compares a provided hash to the actual hash of a file
+// Post sends `body` to a url using the same client and authentication as `Get`
+// Any 2XX status code is considered a success
+func Post(url, contentType string, body []byte) error {
+	client, err := httpClient()
+	if err != nil {
+		return err
+	}
+
+	// Uploads are not retried here, but they are still limited by the metadata timeout
+	ctx := context.Background()
+	if timeout := parseDuration(downloadCfg.MetadataTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := newRequest(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s : Upload status code: %d", url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Verify compares a provided hash to the actual hash of a file.
+// The hash may be prefixed with its algorithm, like `sha512:`, otherwise it is determined by its length.
 func Verify(file string, sha string) bool {
+	expected, err := parseHash(sha)
+	if err != nil {
+		gorillalog.Warn("Unable to verify hash:", err)
+		return false
+	}
 	f, err := os.Open(file)
 	if err != nil {
 		gorillalog.Warn("Unable to open file:", err)
 		return false
 	}
 	defer f.Close()
-	h := sha256.New()
+	h := expected.newHash()
 	if _, err := io.Copy(h, f); err != nil {
 		gorillalog.Warn("Unable to verify hash due to IO error:", err)
 		return false
 	}
-	shaHash := hex.EncodeToString(h.Sum(nil))
-	if shaHash != strings.ToLower(sha) {
+	if !expected.matches(h) {
 		gorillalog.Debug("File hash does not match expected value:", file)
 		return false
 	}
@@ -186,27 +394,40 @@ func Verify(file string, sha string) bool {
 // IfNeeded takes the same values as Download plus a hash as a string
 // It will check if the file already exists, by comparing the hash
 // If the hash does not match, it will attempt to download the file
-// Once downloaded it will attempt to verify the hash again
-func IfNeeded(absFile string, url string, hash string) bool {
-	// If the file exists, check the hash
-	var verified = false
-	if _, err := os.Stat(absFile); err == nil {
-		verified = Verify(absFile, hash)
-	}
-
-	// If hash failed, download the installer
-	if !verified {
-		absPath, _ := filepath.Split(absFile)
-		gorillalog.Info("Downloading", url, "to", absPath)
-		// Download the installer
-		err := File(absPath, url)
+// The download is verified as it is written and only moved into place if the hash matches
+// If the hash is empty, the configured hashless item policy decides if the file is downloaded without verification.
+// A file without a hash is only downloaded if it is not already cached, so changing it needs a new location.
+func IfNeeded(absFile string, url string, hash string) error {
+	// Items without a hash can not be verified
+	var expected *expectedHash
+	if strings.TrimSpace(hash) == "" {
+		if downloadCfg.HashlessItems != HashlessAllow {
+			return ErrNoHash
+		}
+		// There is nothing to compare, so reuse the file if it exists
+		if _, err := os.Stat(absFile); err == nil {
+			return nil
+		}
+		gorillalog.Warn("No hash is set, the download will not be verified:", url)
+	} else {
+		parsed, err := parseHash(hash)
 		if err != nil {
-			gorillalog.Warn("Unable to retrieve package:", url, err)
-			return verified
+			return err
+		}
+		expected = &parsed
+
+		// If the file exists, check the hash
+		if _, err := os.Stat(absFile); err == nil && Verify(absFile, hash) {
+			return nil
 		}
-		verified = Verify(absFile, hash)
 	}
 
-	// return the status of verified
-	return verified
+	// If hash failed, download the installer
+	absPath, _ := filepath.Split(absFile)
+	gorillalog.Info("Downloading", url, "to", absPath)
+	if err := stream(absFile, url, expected); err != nil {
+		gorillalog.Warn("Unable to retrieve package:", url, err)
+		return err
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package download
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,9 +12,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
 )
 
 var (
@@ -223,7 +227,8 @@ func TestFileBasicAuth(t *testing.T) {
 	ts := httptest.NewServer(router())
 	defer ts.Close()
 
-	// Setup basic auth
+	// Setup basic auth for the repo
+	downloadCfg.URL = ts.URL + "/"
 	downloadCfg.AuthUser = "frank"
 	downloadCfg.AuthPass = "beans"
 
@@ -354,9 +359,8 @@ func TestIfNeededValid(t *testing.T) {
 	defer ts.Close()
 
 	// Run the function with our test data and a validHash
-	valid := IfNeeded(tempFile, ts.URL+"/hashtest.txt", validHash)
-	if !valid {
-		t.Error("Unable to download valid file: ", ts.URL+"/hashtest.txt")
+	if err := IfNeeded(tempFile, ts.URL+"/hashtest.txt", validHash); err != nil {
+		t.Error("Unable to download valid file: ", ts.URL+"/hashtest.txt", err)
 	}
 
 	// Get the test file's modification time to see if it was redownloaded
@@ -401,9 +405,8 @@ func TestIfNeededInvalid(t *testing.T) {
 	defer ts.Close()
 
 	// Run the function with our test data and a validHash
-	valid := IfNeeded(tempFile, ts.URL+"/hashtest.txt", validHash)
-	if !valid {
-		t.Error("Unable to download valid file: ", ts.URL+"/hashtest.txt")
+	if err := IfNeeded(tempFile, ts.URL+"/hashtest.txt", validHash); err != nil {
+		t.Error("Unable to download valid file: ", ts.URL+"/hashtest.txt", err)
 	}
 
 	// Get the test file's modification time to see if it was redownloaded
@@ -418,3 +421,251 @@ func TestIfNeededInvalid(t *testing.T) {
 	}
 
 }
+
+// TestPost verifies that a body is uploaded with the configured basic auth
+func TestPost(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || user != "frank" || pass != "beans" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = config.Configuration{ReportURL: ts.URL, AuthUser: "frank", AuthPass: "beans"}
+
+	if err := Post(ts.URL, "application/json", []byte(`{"HostName":"test"}`)); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	if have, want := received, `{"HostName":"test"}`; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+
+	// A rejected upload should return the status code
+	downloadCfg.AuthPass = "wrong"
+	err := Post(ts.URL, "application/json", []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a 401 error, got: %v", err)
+	}
+}
+
+// serveRange serves `testFile` with support for Range requests and records the range received
+func serveRange(ranges *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		content, err := os.ReadFile(testFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		http.ServeContent(w, r, "hashtest.txt", time.Time{}, strings.NewReader(string(content)))
+	}
+}
+
+// TestIfNeededResume verifies that a partial download is resumed with a Range request
+func TestIfNeededResume(t *testing.T) {
+	dir := t.TempDir()
+	absFile := filepath.Join(dir, "hashtest.txt")
+
+	// Leave the first few bytes of the file behind, as if a download was interrupted
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(absFile+partialSuffix, content[:5], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var ranges []string
+	ts := httptest.NewServer(serveRange(&ranges))
+	defer ts.Close()
+
+	if err := IfNeeded(absFile, ts.URL+"/hashtest.txt", validHash); err != nil {
+		t.Fatalf("IfNeeded() did not return a valid file: %v", err)
+	}
+	if have, want := ranges, []string{"bytes=5-"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if !Verify(absFile, validHash) {
+		t.Errorf("Resumed download does not match the expected hash")
+	}
+	if _, err := os.Stat(absFile + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("Partial file was not removed after a successful download")
+	}
+}
+
+// TestIfNeededRestartsStalePartial verifies that a partial file from another version is discarded
+func TestIfNeededRestartsStalePartial(t *testing.T) {
+	dir := t.TempDir()
+	absFile := filepath.Join(dir, "hashtest.txt")
+
+	// A partial file that does not match the start of the real file
+	if err := os.WriteFile(absFile+partialSuffix, []byte("stale data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var ranges []string
+	ts := httptest.NewServer(serveRange(&ranges))
+	defer ts.Close()
+
+	if err := IfNeeded(absFile, ts.URL+"/hashtest.txt", validHash); err != nil {
+		t.Fatalf("IfNeeded() did not return a valid file: %v", err)
+	}
+	if have, want := ranges, []string{"bytes=10-", ""}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+// TestIfNeededHashMismatch verifies an invalid download is never moved into place
+func TestIfNeededHashMismatch(t *testing.T) {
+	dir := t.TempDir()
+	absFile := filepath.Join(dir, "hashtest.txt")
+
+	ts := httptest.NewServer(router())
+	defer ts.Close()
+
+	if err := IfNeeded(absFile, ts.URL+"/hashtest.txt", invalidHash); err == nil {
+		t.Fatalf("IfNeeded() accepted a file with the wrong hash")
+	}
+	for _, path := range []string{absFile, absFile + partialSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Invalid download was left behind: %s", path)
+		}
+	}
+}
+
+// TestFileStatusLeavesNoFile verifies a failed download does not leave an empty file behind
+func TestFileStatusLeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := httptest.NewServer(router())
+	defer ts.Close()
+
+	if err := File(dir, ts.URL+"/404"); err == nil {
+		t.Fatalf("File() did not return an error when returning a 404")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "404")); !os.IsNotExist(err) {
+		t.Errorf("A failed download left a file behind")
+	}
+}
+
+// retryConfig returns a configuration that retries quickly for testing
+func retryConfig() config.Configuration {
+	return config.Configuration{
+		DownloadRetries:          3,
+		DownloadBackoff:          "1ms",
+		DownloadJitter:           "1ms",
+		DownloadRetryStatusCodes: []int{503},
+		MetadataTimeout:          "10s",
+		PackageTimeout:           "10s",
+	}
+}
+
+// TestGetRetries verifies that retryable status codes are tried again
+func TestGetRetries(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = retryConfig()
+
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		serveTestFile(w, r)
+	}))
+	defer ts.Close()
+
+	if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+		t.Fatalf("Get() failed after retries: %v", err)
+	}
+	if have, want := attempts, 3; have != want {
+		t.Errorf("have %d attempts, want %d", have, want)
+	}
+}
+
+// TestGetDoesNotRetryPermanentStatus verifies that other status codes fail right away
+func TestGetDoesNotRetryPermanentStatus(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = retryConfig()
+
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		serve404(w, r)
+	}))
+	defer ts.Close()
+
+	_, err := Get(ts.URL + "/404")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 status error, got: %v", err)
+	}
+	if have, want := attempts, 1; have != want {
+		t.Errorf("have %d attempts, want %d", have, want)
+	}
+}
+
+// TestGetMetadataTimeout verifies the overall timeout applies across attempts
+func TestGetMetadataTimeout(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = retryConfig()
+	downloadCfg.MetadataTimeout = "50ms"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		serveTestFile(w, r)
+	}))
+	defer ts.Close()
+
+	_, err := Get(ts.URL + "/hashtest.txt")
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("expected a timeout error, got: %v", err)
+	}
+}
+
+// TestIfNeededRetryResumes verifies an interrupted transfer is resumed on the next attempt
+func TestIfNeededRetryResumes(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = retryConfig()
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// Send half of the file, then drop the connection
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "hashtest.txt", time.Time{}, strings.NewReader(string(content)))
+	}))
+	defer ts.Close()
+
+	absFile := filepath.Join(t.TempDir(), "hashtest.txt")
+	if err := IfNeeded(absFile, ts.URL+"/hashtest.txt", validHash); err != nil {
+		t.Fatalf("IfNeeded() did not return a valid file: %v", err)
+	}
+	expected := []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}
+	if !reflect.DeepEqual(expected, ranges) {
+		t.Errorf("have %v, want %v", ranges, expected)
+	}
+}
@@ -0,0 +1,72 @@
+package download
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Hashless item policies
+const (
+	HashlessReject = "reject"
+	HashlessAllow  = "allow"
+)
+
+// ErrNoHash is returned when an item without a hash is rejected by the hashless item policy
+var ErrNoHash = errors.New("no hash is set and hashless_items is set to reject")
+
+// hashAlgorithms maps each supported algorithm to its constructor
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// expectedHash is a parsed hash from a catalog
+type expectedHash struct {
+	algorithm string
+	digest    string
+}
+
+// newHash returns a new hash.Hash for the algorithm
+func (e expectedHash) newHash() hash.Hash {
+	return hashAlgorithms[e.algorithm]()
+}
+
+// matches returns true if the sum of `h` is the expected digest
+func (e expectedHash) matches(h hash.Hash) bool {
+	return hex.EncodeToString(h.Sum(nil)) == e.digest
+}
+
+// parseHash accepts a hex digest with an optional algorithm prefix like `sha512:`.
+// Without a prefix, the algorithm is determined by the length of the digest.
+func parseHash(value string) (expectedHash, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	algorithm, digest, prefixed := strings.Cut(value, ":")
+	if !prefixed {
+		digest = algorithm
+		switch len(digest) {
+		case sha256.Size * 2:
+			algorithm = "sha256"
+		case sha512.Size384 * 2:
+			algorithm = "sha384"
+		case sha512.Size * 2:
+			algorithm = "sha512"
+		default:
+			return expectedHash{}, fmt.Errorf("unable to determine the algorithm of hash %q", value)
+		}
+	}
+
+	newHash, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return expectedHash{}, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+	}
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != newHash().Size()*2 {
+		return expectedHash{}, fmt.Errorf("invalid %s hash %q", algorithm, digest)
+	}
+	return expectedHash{algorithm: algorithm, digest: digest}, nil
+}
@@ -0,0 +1,90 @@
+package download
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+var (
+	validHash384 = "beed8c58217d8dfdd516c68907e9271172d091545d65c947262c5fc390da6de7d658dbbb74405ec8142b4d93f3777532"
+	validHash512 = "20a3b34c697a33478efe691badac9199552724526a19123e080f26ec1bf160e721c3d74283b4a1a32b0f4253159be5380e3c7d267c99e2b6c2e5f7e68be028b1"
+)
+
+// TestParseHash verifies the algorithm is read from the prefix or the length of the hash
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		value     string
+		algorithm string
+		wantErr   bool
+	}{
+		{value: validHash, algorithm: "sha256"},
+		{value: "sha256:" + validHashUpper, algorithm: "sha256"},
+		{value: validHash384, algorithm: "sha384"},
+		{value: "SHA384:" + validHash384, algorithm: "sha384"},
+		{value: validHash512, algorithm: "sha512"},
+		{value: "sha512:" + validHash512, algorithm: "sha512"},
+		{value: "md5:d41d8cd98f00b204e9800998ecf8427e", wantErr: true},
+		{value: "sha512:" + validHash, wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		expected, err := parseHash(tt.value)
+		if have, want := err != nil, tt.wantErr; have != want {
+			t.Errorf("%s: have error %v, want error %v", tt.value, err, want)
+			continue
+		}
+		if have, want := expected.algorithm, tt.algorithm; have != want {
+			t.Errorf("%s: have %s, want %s", tt.value, have, want)
+		}
+	}
+}
+
+// TestVerifyAlgorithms verifies files can be checked with each supported algorithm
+func TestVerifyAlgorithms(t *testing.T) {
+	for _, hash := range []string{"sha256:" + validHash, validHash384, "sha512:" + validHash512} {
+		if !Verify(testFile, hash) {
+			t.Errorf("Expected %s to match %s", hash, testFile)
+		}
+	}
+	if Verify(testFile, "sha512:"+invalidHash+invalidHash) {
+		t.Errorf("Expected an invalid sha512 hash not to match")
+	}
+}
+
+// TestIfNeededHashless verifies the hashless item policy
+func TestIfNeededHashless(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	ts := httptest.NewServer(router())
+	defer ts.Close()
+	absFile := filepath.Join(t.TempDir(), "hashtest.txt")
+
+	// Rejected by default
+	downloadCfg = config.Configuration{HashlessItems: HashlessReject}
+	if err := IfNeeded(absFile, ts.URL+"/hashtest.txt", ""); !errors.Is(err, ErrNoHash) {
+		t.Fatalf("Expected ErrNoHash, got: %v", err)
+	}
+	if _, err := os.Stat(absFile); !os.IsNotExist(err) {
+		t.Errorf("Expected a rejected item not to be downloaded")
+	}
+
+	// Downloaded without verification when allowed
+	downloadCfg = config.Configuration{HashlessItems: HashlessAllow}
+	if err := IfNeeded(absFile, ts.URL+"/hashtest.txt", ""); err != nil {
+		t.Fatalf("Expected a hashless item to be allowed, got: %v", err)
+	}
+	if !Verify(absFile, validHash) {
+		t.Errorf("Expected the hashless item to be downloaded")
+	}
+
+	// There is no server this time, so this only works if the cached copy is reused
+	if err := IfNeeded(absFile, "http://127.0.0.1:0/hashtest.txt", ""); err != nil {
+		t.Errorf("Expected the cached hashless item to be reused, got: %v", err)
+	}
+}
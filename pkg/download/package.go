@@ -0,0 +1,125 @@
+package download
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+)
+
+// CASDir is the directory under the cache path that stores packages by their content
+const CASDir = "cas"
+
+// PackagePath returns where a package is kept in the cache.
+// Packages with a hash are stored by content as <cache>/cas/<algorithm>/<digest>/<file name>,
+// so the same package referenced from different locations is only downloaded once.
+// Packages without a hash keep the layout of their location.
+func PackagePath(cachePath, location, hash string) string {
+	if expected, err := parseHash(hash); err == nil {
+		_, fileName := path.Split(location)
+		return filepath.Join(cachePath, CASDir, expected.algorithm, expected.digest, fileName)
+	}
+	return locationPath(cachePath, location)
+}
+
+// locationPath returns where a package was kept before packages were stored by content
+func locationPath(cachePath, location string) string {
+	relPath, fileName := path.Split(location)
+	return filepath.Join(cachePath, relPath, fileName)
+}
+
+// packageURLs returns the urls to try for a package, in order. Each configured package source is
+// tried before `url`. Sources are only used for packages with a hash, since their content
+// can not be trusted otherwise.
+func packageURLs(location, url, hash string) []string {
+	if strings.TrimSpace(hash) == "" {
+		return []string{url}
+	}
+	var urls []string
+	for _, source := range downloadCfg.PackageSources {
+		sourceURL := strings.TrimSuffix(source, "/") + "/" + strings.TrimPrefix(location, "/")
+		if sourceURL != url {
+			urls = append(urls, sourceURL)
+		}
+	}
+	return append(urls, url)
+}
+
+// Package makes sure a valid copy of a package is in the cache and returns its path.
+// Each package source is tried in turn, and the first copy that matches the hash is kept.
+// The modification time of the package is updated so `CleanUp` knows it was recently used.
+func Package(cachePath, location, url, hash string) (string, error) {
+	absFile := PackagePath(cachePath, location, hash)
+	if absFile != locationPath(cachePath, location) {
+		reuseCached(absFile, locationPath(cachePath, location), hash)
+	}
+
+	var err error
+	urls := packageURLs(location, url, hash)
+	for i, sourceURL := range urls {
+		err = IfNeeded(absFile, sourceURL, hash)
+		if err == nil || errors.Is(err, ErrNoHash) {
+			break
+		}
+		if i < len(urls)-1 {
+			gorillalog.Warn("Unable to use package source, trying the next one:", sourceURL, err)
+		}
+	}
+	if err != nil {
+		return absFile, err
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(absFile, now, now); err != nil {
+		gorillalog.Warn("Unable to update cached package time:", absFile, err)
+	}
+	return absFile, nil
+}
+
+// reuseCached fills in a missing package from a copy that is already in the cache.
+// That is either a package stored by its location, or the same content under a different name.
+func reuseCached(absFile, oldFile, hash string) {
+	if _, err := os.Stat(absFile); err == nil {
+		return
+	}
+	dir := filepath.Dir(absFile)
+
+	// Move packages downloaded before they were stored by content
+	if _, err := os.Stat(oldFile); err == nil && Verify(oldFile, hash) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			gorillalog.Warn("Unable to make filepath:", dir, err)
+			return
+		}
+		if err := os.Rename(oldFile, absFile); err != nil {
+			gorillalog.Warn("Unable to move cached package:", oldFile, err)
+			return
+		}
+		gorillalog.Info("Moved cached package", oldFile, "to", absFile)
+		return
+	}
+
+	// Link to the same content stored under a different name
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), partialSuffix) {
+			continue
+		}
+		existing := filepath.Join(dir, entry.Name())
+		if !Verify(existing, hash) {
+			continue
+		}
+		if err := os.Link(existing, absFile); err != nil {
+			gorillalog.Debug("Unable to link cached package:", existing, err)
+			continue
+		}
+		gorillalog.Debug("Linked cached package", existing, "to", absFile)
+		return
+	}
+}
@@ -0,0 +1,173 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// TestPackageMigratesLocationCache verifies packages cached by location are moved instead of downloaded
+func TestPackageMigratesLocationCache(t *testing.T) {
+	cachePath := t.TempDir()
+	oldFile := filepath.Join(cachePath, "packages", "hashtest.txt")
+	if err := os.MkdirAll(filepath.Dir(oldFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := copy(testFile, oldFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// There is no server, so this only works if the cached copy is used
+	absFile, err := Package(cachePath, "packages/hashtest.txt", "http://127.0.0.1:0/hashtest.txt", validHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := absFile, filepath.Join(cachePath, CASDir, "sha256", validHash, "hashtest.txt"); have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be moved", oldFile)
+	}
+}
+
+// TestPackageSharesContent verifies the same content from different locations is only downloaded once
+func TestPackageSharesContent(t *testing.T) {
+	cachePath := t.TempDir()
+	ts := httptest.NewServer(router())
+	defer ts.Close()
+
+	first, err := Package(cachePath, "vendor/hashtest.txt", ts.URL+"/hashtest.txt", "sha256:"+validHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// There is no server for the second location, so this only works if the first download is reused
+	second, err := Package(cachePath, "mirror/renamed.txt", "http://127.0.0.1:0/renamed.txt", validHash)
+	if err != nil {
+		t.Fatalf("Expected the existing content to be reused: %v", err)
+	}
+
+	if have, want := filepath.Dir(second), filepath.Dir(first); have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if !Verify(second, validHash) {
+		t.Errorf("Expected %s to have the same content as %s", second, first)
+	}
+}
+
+// TestPackageSources verifies each source is tried in turn until one matches the hash
+func TestPackageSources(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	// A mirror that does not have the package
+	missing := httptest.NewServer(http.HandlerFunc(serve404))
+	defer missing.Close()
+
+	// A mirror with the wrong content
+	wrong := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not the package"))
+	}))
+	defer wrong.Close()
+
+	// A file share with the right content
+	share := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(share, "packages"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := copy(testFile, filepath.Join(share, "packages", "hashtest.txt")); err != nil {
+		t.Fatal(err)
+	}
+	shareURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(share)}).String()
+
+	downloadCfg = config.Configuration{PackageSources: []string{missing.URL, wrong.URL + "/", shareURL}}
+
+	// The central repo is unreachable, so the file share has to be used
+	absFile, err := Package(t.TempDir(), "packages/hashtest.txt", "http://127.0.0.1:0/packages/hashtest.txt", validHash)
+	if err != nil {
+		t.Fatalf("Expected a package source to be used: %v", err)
+	}
+	if !Verify(absFile, validHash) {
+		t.Errorf("Expected %s to match the catalog hash", absFile)
+	}
+}
+
+// TestPackageURLs verifies sources are only used for packages with a hash
+func TestPackageURLs(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+	downloadCfg = config.Configuration{PackageSources: []string{"file://branch-nas/gorilla/", "http://cache.local:8080"}}
+
+	central := "https://example.com/packages/app/app.msi"
+	expected := []string{
+		"file://branch-nas/gorilla/packages/app/app.msi",
+		"http://cache.local:8080/packages/app/app.msi",
+		central,
+	}
+	if actual := packageURLs("packages/app/app.msi", central, validHash); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+	if actual := packageURLs("packages/app/app.msi", central, ""); !reflect.DeepEqual([]string{central}, actual) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", []string{central}, actual)
+	}
+}
+
+// TestFileRoot verifies file urls with a host refer to a file share
+func TestFileRoot(t *testing.T) {
+	for host, want := range map[string]string{
+		"":          "/",
+		"localhost": "/",
+		"nas":       `\\nas`,
+	} {
+		if have := fileRoot(host); have != want {
+			t.Errorf("%q: have %s, want %s", host, have, want)
+		}
+	}
+}
+
+// TestPackageSourcesWithoutCredentials verifies the repo credentials are not sent to package sources
+func TestPackageSourcesWithoutCredentials(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	var mirrorAuth, repoAuth []string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorAuth = append(mirrorAuth, r.Header.Get("Authorization"))
+		serve404(w, r)
+	}))
+	defer mirror.Close()
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repoAuth = append(repoAuth, r.Header.Get("Authorization"))
+		http.ServeFile(w, r, testFile)
+	}))
+	defer repo.Close()
+
+	downloadCfg = config.Configuration{
+		URL:            repo.URL + "/",
+		AuthUser:       "frank",
+		AuthPass:       "beans",
+		AuthToken:      "s3cr3t",
+		PackageSources: []string{mirror.URL},
+	}
+	if _, err := Package(t.TempDir(), "packages/hashtest.txt", repo.URL+"/packages/hashtest.txt", validHash); err != nil {
+		t.Fatalf("Expected the repo to be used: %v", err)
+	}
+
+	if len(mirrorAuth) == 0 {
+		t.Fatalf("Expected the mirror to be tried first")
+	}
+	for _, auth := range mirrorAuth {
+		if auth != "" {
+			t.Errorf("Expected no Authorization header for the mirror, have %q", auth)
+		}
+	}
+	if len(repoAuth) == 0 || repoAuth[0] != "Bearer s3cr3t" {
+		t.Errorf("Expected the repo to receive the credentials, have %v", repoAuth)
+	}
+}
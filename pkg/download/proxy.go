@@ -0,0 +1,100 @@
+package download
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyFunc returns the function the transport uses to pick a proxy for each request.
+// A configured `proxy_url` takes priority over the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+// Hosts that match `no_proxy` are always reached directly.
+func proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	var configured *url.URL
+	if downloadCfg.ProxyURL != "" {
+		parsed, err := url.Parse(downloadCfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse proxy url: %w", err)
+		}
+		configured = parsed
+	}
+	noProxy := downloadCfg.NoProxy
+	user, pass := downloadCfg.ProxyUser, downloadCfg.ProxyPass
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(noProxy, req.URL) {
+			return nil, nil
+		}
+
+		proxy := configured
+		if proxy == nil {
+			envProxy, err := http.ProxyFromEnvironment(req)
+			if err != nil || envProxy == nil {
+				return envProxy, err
+			}
+			proxy = envProxy
+		}
+
+		// Configured credentials replace any in the proxy url
+		if user != "" {
+			withAuth := *proxy
+			withAuth.User = url.UserPassword(user, pass)
+			proxy = &withAuth
+		}
+		return proxy, nil
+	}, nil
+}
+
+// bypassProxy returns true if `target` matches an entry in `noProxy`.
+// Entries may be "*", a host name, a domain like ".example.com", an IP address or CIDR range,
+// and may include a port to only match that port.
+func bypassProxy(noProxy []string, target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	port := target.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[target.Scheme]
+	}
+	hostIP := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		// CIDR ranges never include a port
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if hostIP != nil && network.Contains(hostIP) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if hostIP != nil && entryIP.Equal(hostIP) {
+				return true
+			}
+			continue
+		}
+
+		// "example.com" and ".example.com" both match the domain and its subdomains
+		domain := strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
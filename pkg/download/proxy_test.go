@@ -0,0 +1,86 @@
+package download
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// TestGetProxy verifies requests are sent through the configured proxy with its credentials
+func TestGetProxy(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	var proxied []string
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("gorilla:secret"))
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != wantAuth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		proxied = append(proxied, r.URL.Host)
+		serveTestFile(w, r)
+	}))
+	defer proxy.Close()
+
+	// The repo host does not exist, so this only works through the proxy
+	downloadCfg = config.Configuration{ProxyURL: proxy.URL, ProxyUser: "gorilla", ProxyPass: "secret"}
+	if _, err := Get("http://repo.example.com/hashtest.txt"); err != nil {
+		t.Fatalf("Expected the request to go through the proxy: %v", err)
+	}
+	if have, want := len(proxied), 1; have != want || proxied[0] != "repo.example.com" {
+		t.Errorf("Expected one request for repo.example.com, have %v", proxied)
+	}
+
+	// Hosts in no_proxy go direct
+	ts := httptest.NewServer(router())
+	defer ts.Close()
+	downloadCfg.NoProxy = []string{"127.0.0.1"}
+	if _, err := Get(ts.URL + "/hashtest.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(proxied), 1; have != want {
+		t.Errorf("Expected no_proxy hosts to bypass the proxy, have %v", proxied)
+	}
+
+	// Wrong credentials are rejected by the proxy
+	downloadCfg = config.Configuration{ProxyURL: proxy.URL, ProxyUser: "gorilla", ProxyPass: "wrong"}
+	if _, err := Get("http://repo.example.com/hashtest.txt"); err == nil {
+		t.Errorf("Expected the proxy to reject the wrong credentials")
+	}
+}
+
+// TestBypassProxy verifies each kind of no_proxy entry
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"localhost", ".corp.example.com", "example.org", "10.0.0.0/8", "192.168.1.5", "cache.local:8080"}
+	tests := map[string]bool{
+		"http://localhost/catalogs/production.yaml": true,
+		"https://repo.corp.example.com/app.msi":     true,
+		"https://corp.example.com/app.msi":          true,
+		"https://notcorp.example.com/app.msi":       false,
+		"https://www.example.org/app.msi":           true,
+		"https://example.org.evil.com/app.msi":      false,
+		"http://10.1.2.3/app.msi":                   true,
+		"http://11.1.2.3/app.msi":                   false,
+		"http://192.168.1.5/app.msi":                true,
+		"http://cache.local:8080/app.msi":           true,
+		"http://cache.local/app.msi":                false,
+		"https://example.com/app.msi":               false,
+	}
+	for rawURL, want := range tests {
+		target, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := bypassProxy(noProxy, target); have != want {
+			t.Errorf("%s: have %v, want %v", rawURL, have, want)
+		}
+	}
+	if target, _ := url.Parse("https://anything.example.net/"); !bypassProxy([]string{"*"}, target) {
+		t.Errorf("Expected * to bypass the proxy for every host")
+	}
+}
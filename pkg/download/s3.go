@@ -0,0 +1,205 @@
+package download
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the sha256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Credentials are the keys used to sign requests to object storage
+type s3Credentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// s3Transport serves `s3://bucket/key` urls from S3 compatible object storage,
+// signing each request with AWS Signature Version 4
+type s3Transport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t s3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := s3URL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL = target
+	out.Host = target.Host
+
+	// Object storage only understands its own signature, so any other authentication is removed
+	out.Header.Del("Authorization")
+	if creds := currentS3Credentials(); creds.accessKeyID != "" {
+		signV4(out, creds, s3Region(), time.Now())
+	}
+	return t.base.RoundTrip(out)
+}
+
+// currentS3Credentials returns the configured credentials, or the standard AWS environment variables
+func currentS3Credentials() s3Credentials {
+	if downloadCfg.S3AccessKeyID != "" {
+		return s3Credentials{
+			accessKeyID:     downloadCfg.S3AccessKeyID,
+			secretAccessKey: downloadCfg.S3SecretAccessKey,
+			sessionToken:    downloadCfg.S3SessionToken,
+		}
+	}
+	return s3Credentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// s3Region returns the configured region, the region from the environment, or "us-east-1"
+func s3Region() string {
+	for _, region := range []string{downloadCfg.S3Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if region != "" {
+			return region
+		}
+	}
+	return "us-east-1"
+}
+
+// s3URL returns the http url of an `s3://bucket/key` url.
+// A configured `s3_endpoint` is addressed with the bucket in the path, as MinIO and most other
+// S3 compatible servers expect. Otherwise the AWS endpoint for the region is used with the bucket in the host.
+func s3URL(s3 *url.URL) (*url.URL, error) {
+	bucket := s3.Host
+	if bucket == "" {
+		return nil, fmt.Errorf("%s : No bucket in url", s3)
+	}
+
+	target := &url.URL{Scheme: "https", Host: bucket + ".s3." + s3Region() + ".amazonaws.com", Path: s3.Path}
+	if downloadCfg.S3Endpoint != "" {
+		endpoint, err := url.Parse(downloadCfg.S3Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse s3 endpoint: %w", err)
+		}
+		target = &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: strings.TrimSuffix(endpoint.Path, "/") + "/" + bucket + s3.Path}
+	}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+	target.RawPath = s3Escape(target.Path, false)
+	target.RawQuery = s3.RawQuery
+	return target, nil
+}
+
+// s3Escape encodes everything but unreserved characters, as Signature Version 4 requires.
+// Slashes are kept unless `encodeSlash` is true.
+func s3Escape(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signV4 adds an AWS Signature Version 4 for the time `t` to a request.
+// Every header already on the request is signed, so it must be called after they are all set.
+func signV4(req *http.Request, creds s3Credentials, region string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	scope := strings.Join([]string{t.Format("20060102"), region, "s3", "aws4_request"}, "/")
+
+	// The body of an upload is not read twice to hash it
+	payloadHash := emptyPayloadHash
+	if req.Body != nil && req.Body != http.NoBody {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	// Headers are signed by their lower case name, in order, with their values trimmed
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.secretAccessKey)
+	for _, part := range []string{t.Format("20060102"), region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query parameters sorted and encoded for signing
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	slices.Sort(params)
+	return strings.Join(params, "&")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of `data` with `key`
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
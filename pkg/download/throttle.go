@@ -0,0 +1,119 @@
+package download
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// rateLimiter is a token bucket shared by every package download, so concurrent downloads
+// stay within the limit together
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+// packageLimiter limits package downloads to the configured rate
+var packageLimiter = &rateLimiter{}
+
+// wait blocks until `n` bytes may be read at `rate` bytes per second
+func (l *rateLimiter) wait(ctx context.Context, rate int64, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.rate != float64(rate) || l.last.IsZero() {
+		// Start over with a full second of tokens when the rate changes
+		l.rate = float64(rate)
+		l.tokens = l.rate
+		l.last = now
+	}
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+	l.last = now
+
+	// Take the tokens now, and wait for any we do not have yet
+	l.tokens -= float64(n)
+	delay := time.Duration(0)
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttledReader limits how quickly a download is read
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rateLimiter
+	rate    int64
+}
+
+// Read implements io.Reader, reading no more than one second worth of data at a time
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > t.rate {
+		p = p[:t.rate]
+	}
+	n, err := t.reader.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.wait(t.ctx, t.rate, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// rateLimit returns the package download limit in bytes per second at time `now`, or 0 for no limit.
+// Service mode uses its own limit if one is configured, and there is no limit during the full speed hours.
+func rateLimit(now time.Time) int64 {
+	limit := downloadCfg.DownloadRateLimit
+	if downloadCfg.ServiceMode && downloadCfg.ServiceDownloadRateLimit != "" {
+		limit = downloadCfg.ServiceDownloadRateLimit
+	}
+	if limit == "" || inHours(downloadCfg.FullSpeedHours, now) {
+		return 0
+	}
+	// `config.Get` has already validated the limit
+	rate, _ := config.ParseSize(limit)
+	return rate
+}
+
+// inHours returns true if the local time of `now` is within a range like "18:00-07:00"
+func inHours(hours string, now time.Time) bool {
+	if hours == "" {
+		return false
+	}
+	start, end, err := config.ParseHours(hours)
+	if err != nil {
+		return false
+	}
+	sinceMidnight := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if start <= end {
+		return sinceMidnight >= start && sinceMidnight < end
+	}
+	// The range wraps past midnight
+	return sinceMidnight >= start || sinceMidnight < end
+}
+
+// throttle limits `reader` to the current package download rate
+func throttle(ctx context.Context, reader io.Reader) io.Reader {
+	rate := rateLimit(time.Now())
+	if rate <= 0 {
+		return reader
+	}
+	return &throttledReader{ctx: ctx, reader: reader, limiter: packageLimiter, rate: rate}
+}
@@ -0,0 +1,69 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// TestRateLimit verifies which limit applies in each mode and time
+func TestRateLimit(t *testing.T) {
+	origCfg := downloadCfg
+	defer func() { downloadCfg = origCfg }()
+
+	noon := time.Date(2026, 3, 4, 12, 0, 0, 0, time.Local)
+	night := time.Date(2026, 3, 4, 23, 30, 0, 0, time.Local)
+	early := time.Date(2026, 3, 4, 6, 59, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		cfg  config.Configuration
+		now  time.Time
+		want int64
+	}{
+		{name: "no limit", cfg: config.Configuration{}, now: noon, want: 0},
+		{name: "limit", cfg: config.Configuration{DownloadRateLimit: "2MB"}, now: noon, want: 2 << 20},
+		{name: "service limit", cfg: config.Configuration{DownloadRateLimit: "2MB", ServiceDownloadRateLimit: "512KB", ServiceMode: true}, now: noon, want: 512 << 10},
+		{name: "service limit outside service", cfg: config.Configuration{DownloadRateLimit: "2MB", ServiceDownloadRateLimit: "512KB"}, now: noon, want: 2 << 20},
+		{name: "full speed at night", cfg: config.Configuration{DownloadRateLimit: "2MB", FullSpeedHours: "18:00-07:00"}, now: night, want: 0},
+		{name: "full speed early", cfg: config.Configuration{DownloadRateLimit: "2MB", FullSpeedHours: "18:00-07:00"}, now: early, want: 0},
+		{name: "limited during the day", cfg: config.Configuration{DownloadRateLimit: "2MB", FullSpeedHours: "18:00-07:00"}, now: noon, want: 2 << 20},
+		{name: "daytime window", cfg: config.Configuration{DownloadRateLimit: "2MB", FullSpeedHours: "11:00-13:00"}, now: noon, want: 0},
+	}
+	for _, tt := range tests {
+		downloadCfg = tt.cfg
+		if have := rateLimit(tt.now); have != tt.want {
+			t.Errorf("%s: have %d, want %d", tt.name, have, tt.want)
+		}
+	}
+}
+
+// TestThrottledReader verifies reads are slowed to the rate limit
+func TestThrottledReader(t *testing.T) {
+	data := make([]byte, 6000)
+	reader := &throttledReader{
+		ctx:     context.Background(),
+		reader:  bytes.NewReader(data),
+		limiter: &rateLimiter{},
+		rate:    4000,
+	}
+
+	// The first second worth of data is available right away, the rest takes another half second
+	start := time.Now()
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	if have, want := n, int64(len(data)); have != want {
+		t.Errorf("have %d bytes, want %d", have, want)
+	}
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the read to be throttled, it took %s", elapsed)
+	}
+}
@@ -140,9 +140,10 @@ func Warn(logStrings ...interface{}) {
 	log.Println(logStrings...)
 }
 
-// Error logs a string a ERROR
-// We print to stdout, write to disk, and then panic
+// Error logs a string as ERROR
+// We print to stdout and write to disk, callers decide how to handle the error
 func Error(logStrings ...interface{}) {
+	fmt.Println(logStrings...)
 	if checkonly {
 		return
 	}
@@ -150,7 +151,7 @@ func Error(logStrings ...interface{}) {
 	defer logMu.Unlock()
 	rotateCurrentLogIfNeeded()
 	log.SetPrefix("ERROR: ")
-	log.Panic(logStrings...)
+	log.Println(logStrings...)
 }
 
 func rotateCurrentLogIfNeeded() {
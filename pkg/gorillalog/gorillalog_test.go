@@ -254,12 +254,8 @@ func ExampleError() {
 	// Set up what we expect
 	logString := "Error String!"
 
-	// Prepare to recover from a panic
-	defer func() {
-		recover()
-	}()
-
-	// Run the function
+	// Run the function, it should not panic
 	Error(logString)
 	// Output:
+	// Error String!
 }
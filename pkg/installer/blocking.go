@@ -0,0 +1,113 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/running"
+)
+
+// What to do when an item's `blocking_apps` are running
+const (
+	BlockingSkip      = "skip"
+	BlockingWait      = "wait"
+	BlockingTerminate = "terminate"
+)
+
+// defaultBlockingTimeout is how long the wait policy waits for blocking apps to close
+const defaultBlockingTimeout = 5 * time.Minute
+
+// terminateTimeout is how long terminated apps are given to exit
+const terminateTimeout = 10 * time.Second
+
+var (
+	// These abstractions allows us to override when testing
+	processLister        running.Lister = running.System{}
+	blockingPollInterval                = 5 * time.Second
+)
+
+// blockingApps returns the processes that block an item from being installed or uninstalled
+func blockingApps(item catalog.Item) ([]running.Process, error) {
+	processes, err := processLister.Processes()
+	if err != nil {
+		return nil, err
+	}
+	return running.Match(processes, item.BlockingApps), nil
+}
+
+// describe lists processes for a log or report
+func describe(processes []running.Process) string {
+	names := make([]string, len(processes))
+	for i, process := range processes {
+		names[i] = fmt.Sprintf("%s (%d)", process.Name, process.PID)
+	}
+	return strings.Join(names, ", ")
+}
+
+// waitForExit polls until none of the item's blocking apps are running or the timeout passes,
+// and returns any that are still running
+func waitForExit(item catalog.Item, timeout time.Duration) ([]running.Process, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		blocking, err := blockingApps(item)
+		if err != nil || len(blocking) == 0 || !time.Now().Before(deadline) {
+			return blocking, err
+		}
+		time.Sleep(min(blockingPollInterval, time.Until(deadline)))
+	}
+}
+
+// checkBlockingApps applies the item's blocking apps policy and returns an error if any of them are still running.
+// The default policy skips the item; "wait" gives the apps time to close, and "terminate" stops them.
+func checkBlockingApps(item catalog.Item) error {
+	if len(item.BlockingApps) == 0 {
+		return nil
+	}
+	blocking, err := blockingApps(item)
+	if err != nil {
+		// Not being able to list processes should not stop every install
+		gorillalog.Warn("Unable to check blocking apps for", item.DisplayName, err)
+		return nil
+	}
+	if len(blocking) == 0 {
+		return nil
+	}
+
+	switch strings.ToLower(item.BlockingAppsAction) {
+	case BlockingWait:
+		timeout := defaultBlockingTimeout
+		if item.BlockingAppsTimeout != "" {
+			parsed, err := time.ParseDuration(item.BlockingAppsTimeout)
+			if err != nil {
+				gorillalog.Warn("Invalid blocking_apps_timeout for", item.DisplayName, err)
+			} else {
+				timeout = parsed
+			}
+		}
+		gorillalog.Info("Waiting up to", timeout, "for blocking apps to close:", describe(blocking))
+		blocking, err = waitForExit(item, timeout)
+	case BlockingTerminate:
+		for _, process := range blocking {
+			gorillalog.Info("Terminating blocking app for", item.DisplayName, process.Name, process.PID)
+			if err := processLister.Terminate(process.PID); err != nil {
+				gorillalog.Warn("Unable to terminate", process.Name, process.PID, err)
+			}
+		}
+		blocking, err = waitForExit(item, terminateTimeout)
+	case "", BlockingSkip:
+	default:
+		gorillalog.Warn("Unsupported blocking_apps_action for", item.DisplayName, item.BlockingAppsAction)
+	}
+	if err != nil {
+		gorillalog.Warn("Unable to check blocking apps for", item.DisplayName, err)
+		return nil
+	}
+
+	if len(blocking) > 0 {
+		return fmt.Errorf("blocked by running applications: %s", describe(blocking))
+	}
+	return nil
+}
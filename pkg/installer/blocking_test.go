@@ -0,0 +1,125 @@
+package installer
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/report"
+	"github.com/1dustindavis/gorilla/pkg/running"
+)
+
+// fakeLister pretends a set of processes is running
+type fakeLister struct {
+	mu         sync.Mutex
+	processes  []running.Process
+	terminated []int
+	// exitAfter removes every process after this many calls to `Processes`, if it is above 0
+	exitAfter int
+	calls     int
+}
+
+func (f *fakeLister) Processes() ([]running.Process, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.exitAfter > 0 && f.calls > f.exitAfter {
+		f.processes = nil
+	}
+	return slices.Clone(f.processes), nil
+}
+
+func (f *fakeLister) Terminate(pid int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.terminated = append(f.terminated, pid)
+	f.processes = slices.DeleteFunc(f.processes, func(p running.Process) bool { return p.PID == pid })
+	return nil
+}
+
+// useLister replaces the process lister for a test
+func useLister(t *testing.T, lister running.Lister) {
+	origLister, origInterval := processLister, blockingPollInterval
+	processLister, blockingPollInterval = lister, time.Millisecond
+	t.Cleanup(func() { processLister, blockingPollInterval = origLister, origInterval })
+}
+
+// TestCheckBlockingApps verifies each blocking apps policy
+func TestCheckBlockingApps(t *testing.T) {
+	chrome := []running.Process{{PID: 100, Name: "chrome.exe"}, {PID: 200, Name: "explorer.exe"}}
+	item := catalog.Item{DisplayName: "Google Chrome", BlockingApps: []string{"chrome"}}
+
+	// Skipped by default
+	useLister(t, &fakeLister{processes: chrome})
+	err := checkBlockingApps(item)
+	if err == nil || !strings.Contains(err.Error(), "chrome.exe (100)") {
+		t.Errorf("Expected the item to be blocked by chrome.exe, got: %v", err)
+	}
+
+	// Not blocked when the apps are not running
+	useLister(t, &fakeLister{processes: chrome[1:]})
+	if err := checkBlockingApps(item); err != nil {
+		t.Errorf("Expected the item not to be blocked: %v", err)
+	}
+
+	// Waiting until the apps close
+	item.BlockingAppsAction = BlockingWait
+	item.BlockingAppsTimeout = "1s"
+	useLister(t, &fakeLister{processes: chrome, exitAfter: 3})
+	if err := checkBlockingApps(item); err != nil {
+		t.Errorf("Expected the item to wait for chrome.exe to close: %v", err)
+	}
+
+	// Waiting for apps that do not close
+	item.BlockingAppsTimeout = "20ms"
+	useLister(t, &fakeLister{processes: chrome})
+	if err := checkBlockingApps(item); err == nil {
+		t.Errorf("Expected the item to be blocked after waiting")
+	}
+
+	// Terminating the apps
+	item.BlockingAppsAction = BlockingTerminate
+	lister := &fakeLister{processes: chrome}
+	useLister(t, lister)
+	if err := checkBlockingApps(item); err != nil {
+		t.Errorf("Expected the item not to be blocked after terminating chrome.exe: %v", err)
+	}
+	if have, want := lister.terminated, []int{100}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have terminated %v, want %v", have, want)
+	}
+}
+
+// TestInstallBlocked verifies a blocked item is skipped and reported
+func TestInstallBlocked(t *testing.T) {
+	statusCheck = fakeCheckStatus
+	installItemFunc = fakeInstallItem
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+	useLister(t, &fakeLister{processes: []running.Process{{PID: 100, Name: "chrome.exe"}}})
+
+	installItemURL = ""
+	item := msiItem
+	item.DisplayName = statusActionNoError
+	item.BlockingApps = []string{"chrome.exe"}
+	rpt := &report.RunReport{}
+
+	have, err := Install(item, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+	if err != nil {
+		t.Errorf("Expected no error: %v", err)
+	}
+	if want := "Blocked by running applications"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if installItemURL != "" {
+		t.Errorf("Expected the installer not to run")
+	}
+	if have, want := rpt.Items[0].Outcome, report.OutcomeBlocked; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
@@ -1,90 +1,120 @@
 package installer
 
 import (
-	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/command"
+	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
 	"github.com/1dustindavis/gorilla/pkg/report"
+	"github.com/1dustindavis/gorilla/pkg/script"
 	"github.com/1dustindavis/gorilla/pkg/status"
 )
 
 var (
+	// A package level copy of our config for the `installer` package to reference
+	installerCfg config.Configuration
+
 	// Base command for each installer type
 	commandNupkg = filepath.Join(os.Getenv("ProgramData"), "chocolatey/bin/choco.exe")
 	commandMsi   = filepath.Join(os.Getenv("WINDIR"), "system32/", "msiexec.exe")
 	commandPs1   = filepath.Join(os.Getenv("WINDIR"), "system32/", "WindowsPowershell", "v1.0", "powershell.exe")
 
 	// These abstractions allows us to override when testing
-	execCommand       = exec.Command
-	statusCheckStatus = status.CheckStatus
-	runCommand        = runCMD
+	execCommand = exec.Command
+	statusCheck = status.Check
+	runCommand  = runCMD
 
 	// Stores url where we will download an item
 	installerURL   string
 	uninstallerURL string
 )
 
-// runCommand executes a command and it's argurments in the CMD environment
-func runCMD(command string, arguments []string) (string, error) {
-	cmd := execCommand(command, arguments...)
-	var cmdOutput []string
-	cmdReader, err := cmd.StdoutPipe()
-	if err != nil {
-		gorillalog.Warn("command:", command, arguments)
-		gorillalog.Warn("Error creating pipe to stdout", err)
-	}
+// SetConfig accepts a configuration struct that all functions in the `installer` package will use
+func SetConfig(cfg config.Configuration) {
+	installerCfg = cfg
+}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	scanner := bufio.NewScanner(cmdReader)
-	gorillalog.Debug("command:", command, arguments)
-	go func() {
-		gorillalog.Debug("Command Output:")
-		gorillalog.Debug("--------------------")
-		for scanner.Scan() {
-			gorillalog.Debug(scanner.Text())
-			cmdOutput = append(cmdOutput, scanner.Text())
+// outputTailLines is the number of trailing lines of installer output kept in the report
+const outputTailLines = 20
+
+// outputWriter collects the output of a command line by line, logging each line as it arrives
+type outputWriter struct {
+	partial []byte
+	lines   []string
+}
+
+// Write logs and keeps every complete line in `p`, holding on to any partial line until the rest arrives
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
 		}
-		gorillalog.Debug("--------------------")
-		wg.Done()
-	}()
+		w.addLine(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
 
-	err = cmd.Start()
-	if err != nil {
-		gorillalog.Warn("command:", command, arguments)
-		gorillalog.Warn("Error running command:", err)
+// addLine logs and keeps one line of output
+func (w *outputWriter) addLine(line string) {
+	line = strings.TrimSuffix(line, "\r")
+	gorillalog.Debug(line)
+	w.lines = append(w.lines, line)
+}
+
+// String returns all of the output, including a final line without a newline
+func (w *outputWriter) String() string {
+	if len(w.partial) > 0 {
+		w.addLine(string(w.partial))
+		w.partial = nil
 	}
+	return strings.Join(w.lines, "\n")
+}
 
-	wg.Wait()
-	err = cmd.Wait()
+// runCommand executes a command and it's argurments in the CMD environment,
+// killing it and anything it started if it runs longer than `timeout`.
+// Output is written straight to a buffer, so a process left holding stdout open
+// can only delay the result by the command's `WaitDelay`.
+func runCMD(name string, arguments []string, timeout time.Duration) (string, error) {
+	cmd := execCommand(name, arguments...)
+	var cmdOutput outputWriter
+	cmd.Stdout = &cmdOutput
+
+	gorillalog.Debug("command:", name, arguments)
+	gorillalog.Debug("Command Output:")
+	gorillalog.Debug("--------------------")
+	err := command.Run(cmd, timeout)
+	output := cmdOutput.String()
+	gorillalog.Debug("--------------------")
 	if err != nil {
-		gorillalog.Warn("command:", command, arguments)
+		gorillalog.Warn("command:", name, arguments)
 		gorillalog.Warn("Command error:", err)
 	}
 
-	return strings.Join(cmdOutput, "\n"), err
+	return output, err
 }
 
 // Get a Nupkg's id using `choco list`
 func getNupkgIDs(nupkgDir, versionArg string) ([]string, error) {
 
 	// Compile the arguments needed to get the id
-	command := commandNupkg
+	nupkgCmd := commandNupkg
 	arguments := []string{"list", versionArg, "--id-only", "-r", "-s", nupkgDir}
 
 	// Run the command and parse each non-empty line as a candidate id
-	cmdOut, cmdErr := runCommand(command, arguments)
+	cmdOut, cmdErr := runCommand(nupkgCmd, arguments, command.Timeout(installerCfg.ScriptTimeout))
 	outputLines := strings.Split(cmdOut, "\n")
 	ids := make([]string, 0, len(outputLines))
 	for _, line := range outputLines {
@@ -124,19 +154,14 @@ func resolveNupkgID(itemName, nupkgDir, versionArg, packageID string) (string, e
 	return ids[0], nil
 }
 
-func installItem(item catalog.Item, itemURL, cachePath string) string {
-
-	// Determine the paths needed for download and install
-	relPath, fileName := path.Split(item.Installer.Location)
-	absPath := filepath.Join(cachePath, relPath)
-	absFile := filepath.Join(absPath, fileName)
+func installItem(item catalog.Item, itemURL, cachePath string) (string, error) {
 
 	// Download the item if it is needed
-	valid := download.IfNeeded(absFile, itemURL, item.Installer.Hash)
-	if !valid {
-		msg := fmt.Sprint("Unable to download valid file: ", itemURL)
+	absFile, err := download.Package(cachePath, item.Installer.Location, itemURL, item.Installer.Hash)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to download valid file %s: %v", itemURL, err)
 		gorillalog.Warn(msg)
-		return msg
+		return msg, errors.New(msg)
 	}
 
 	// Determine the install type and command to pass
@@ -159,7 +184,7 @@ func installItem(item catalog.Item, itemURL, cachePath string) string {
 		if err != nil {
 			msg := fmt.Sprintf("Unable to determine nupkg id for %s: %v", item.DisplayName, err)
 			gorillalog.Warn(msg)
-			return msg
+			return msg, err
 		}
 
 		// Now pass the id along with the parent directory
@@ -192,38 +217,32 @@ func installItem(item catalog.Item, itemURL, cachePath string) string {
 	} else {
 		msg := fmt.Sprint("Unsupported installer type", item.Installer.Type)
 		gorillalog.Warn(msg)
-		return msg
+		return msg, errors.New(msg)
 	}
 
 	// Run the command
-	installerOut, errOut := runCommand(installCmd, installArgs)
+	installerOut, errOut := runCommand(installCmd, installArgs, installerTimeout(item))
 
-	// Write success/failure event to log
-	if errOut != nil {
+	// Write success/failure event to log, some non-zero exit codes mean success with a reboot required
+	if ok, needsReboot := exitResult(item.Installer, errOut); !ok {
 		gorillalog.Warn(item.DisplayName, item.Version, "Installation FAILED")
+	} else if needsReboot {
+		gorillalog.Info(item.DisplayName, item.Version, "Installation SUCCESSFUL, reboot required")
 	} else {
 		gorillalog.Info(item.DisplayName, item.Version, "Installation SUCCESSFUL")
 	}
 
-	// Add the item to InstalledItems in GorillaReport
-	report.InstalledItems = append(report.InstalledItems, item)
-
-	return installerOut
+	return installerOut, errOut
 }
 
-func uninstallItem(item catalog.Item, itemURL, cachePath string) string {
-
-	// Determine the paths needed for download and uinstall
-	relPath, fileName := path.Split(item.Uninstaller.Location)
-	absPath := filepath.Join(cachePath, relPath)
-	absFile := filepath.Join(absPath, fileName)
+func uninstallItem(item catalog.Item, itemURL, cachePath string) (string, error) {
 
 	// Download the item if it is needed
-	valid := download.IfNeeded(absFile, itemURL, item.Uninstaller.Hash)
-	if !valid {
-		msg := fmt.Sprint("Unable to download valid file: ", itemURL)
+	absFile, err := download.Package(cachePath, item.Uninstaller.Location, itemURL, item.Uninstaller.Hash)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to download valid file %s: %v", itemURL, err)
 		gorillalog.Warn(msg)
-		return msg
+		return msg, errors.New(msg)
 	}
 
 	// Determine the uninstall type and build the command
@@ -247,7 +266,7 @@ func uninstallItem(item catalog.Item, itemURL, cachePath string) string {
 		if err != nil {
 			msg := fmt.Sprintf("Unable to determine nupkg id for %s: %v", item.DisplayName, err)
 			gorillalog.Warn(msg)
-			return msg
+			return msg, err
 		}
 
 		// Now pass the id along with the parent directory
@@ -279,97 +298,74 @@ func uninstallItem(item catalog.Item, itemURL, cachePath string) string {
 	} else {
 		msg := fmt.Sprint("Unsupported uninstaller type", item.Uninstaller.Type)
 		gorillalog.Warn(msg)
-		return msg
+		return msg, errors.New(msg)
 	}
 
 	// Run the command
-	uninstallerOut, errOut := runCommand(uninstallCmd, uninstallArgs)
+	uninstallerOut, errOut := runCommand(uninstallCmd, uninstallArgs, installerTimeout(item))
 
-	// Write success/failure event to log
-	if errOut != nil {
+	// Write success/failure event to log, some non-zero exit codes mean success with a reboot required
+	if ok, needsReboot := exitResult(item.Uninstaller, errOut); !ok {
 		gorillalog.Warn(item.DisplayName, item.Version, "Uninstallation FAILED")
+	} else if needsReboot {
+		gorillalog.Info(item.DisplayName, item.Version, "Uninstallation SUCCESSFUL, reboot required")
 	} else {
 		gorillalog.Info(item.DisplayName, item.Version, "Uninstallation SUCCESSFUL")
 	}
 
-	// Add the item to InstalledItems in GorillaReport
-	report.UninstalledItems = append(report.UninstalledItems, item)
-
-	return uninstallerOut
+	return uninstallerOut, errOut
 }
 
-func preinstallScript(catalogItem catalog.Item, cachePath string) (actionNeeded bool, checkErr error) {
-	if err := os.MkdirAll(cachePath, 0755); err != nil {
-		return false, err
-	}
-
-	// Write InstallCheckScript to disk as a Powershell file
-	tmpScript := filepath.Join(cachePath, "tmpPostScript.ps1")
-	if err := os.WriteFile(tmpScript, []byte(catalogItem.PreScript), 0755); err != nil {
-		return false, err
-	}
+// runNopkg runs the script of a script only item in place of an installer or uninstaller,
+// and adds its output to the item's result
+func runNopkg(item catalog.Item, pkg catalog.InstallerItem, phase, action, cachePath string, result *report.ItemResult) (string, error) {
+	gorillalog.Info("Running", phase, "script for", item.DisplayName)
+	scriptResult, err := script.Run(script.Script{
+		Body:        pkg.Script,
+		Interpreter: item.ScriptInterpreter,
+		Phase:       phase,
+		Action:      action,
+		Item:        item,
+		CachePath:   cachePath,
+		Timeout:     installerTimeout(item),
+		Command:     execCommand,
+	})
+	result.Scripts = append(result.Scripts, scriptReport(scriptResult))
 
-	// Build the command to execute the script
-	psCmd := filepath.Join(os.Getenv("WINDIR"), "system32/", "WindowsPowershell", "v1.0", "powershell.exe")
-	psArgs := []string{"-NoProfile", "-NoLogo", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", tmpScript}
-
-	// Execute the script
-	cmd := execCommand(psCmd, psArgs...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	cmdSuccess := cmd.ProcessState.Success()
-	outStr, errStr := stdout.String(), stderr.String()
-
-	// Delete the temporary script
-	if err := os.Remove(tmpScript); err != nil && !os.IsNotExist(err) {
-		gorillalog.Warn("Unable to remove temporary preinstall script:", tmpScript, err)
+	// Write success/failure event to log
+	if ok, _ := exitResult(pkg, err); !ok {
+		gorillalog.Warn(item.DisplayName, item.Version, phase, "script FAILED")
+	} else {
+		gorillalog.Info(item.DisplayName, item.Version, phase, "script SUCCESSFUL")
 	}
 
-	// Log results
-	gorillalog.Debug("Command Error:", err)
-	gorillalog.Debug("stdout:", outStr)
-	gorillalog.Debug("stderr:", errStr)
-
-	return cmdSuccess, err
+	return scriptResult.Stdout, err
 }
 
-func postinstallScript(catalogItem catalog.Item, cachePath string) (actionNeeded bool, checkErr error) {
-	if err := os.MkdirAll(cachePath, 0755); err != nil {
-		return false, err
-	}
-
-	// Write InstallCheckScript to disk as a Powershell file
-	tmpScript := filepath.Join(cachePath, "tmpPostScript.ps1")
-	if err := os.WriteFile(tmpScript, []byte(catalogItem.PostScript), 0755); err != nil {
-		return false, err
-	}
+// runScript runs one of the item's scripts and adds its output to the item's result
+func runScript(item catalog.Item, phase, body, action, cachePath string, result *report.ItemResult) error {
+	scriptResult, err := script.Run(script.Script{
+		Body:        body,
+		Interpreter: item.ScriptInterpreter,
+		Phase:       phase,
+		Action:      action,
+		Item:        item,
+		CachePath:   cachePath,
+		Timeout:     scriptTimeout(item),
+		Command:     execCommand,
+	})
+	result.Scripts = append(result.Scripts, scriptReport(scriptResult))
+	return err
+}
 
-	// Build the command to execute the script
-	psCmd := filepath.Join(os.Getenv("WINDIR"), "system32/", "WindowsPowershell", "v1.0", "powershell.exe")
-	psArgs := []string{"-NoProfile", "-NoLogo", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", tmpScript}
-
-	// Execute the script
-	cmd := execCommand(psCmd, psArgs...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	cmdSuccess := cmd.ProcessState.Success()
-	outStr, errStr := stdout.String(), stderr.String()
-
-	// Delete the temporary script
-	if err := os.Remove(tmpScript); err != nil && !os.IsNotExist(err) {
-		gorillalog.Warn("Unable to remove temporary postinstall script:", tmpScript, err)
+// scriptReport returns the part of a script's result that is kept in the report
+func scriptReport(scriptResult script.Result) report.ScriptResult {
+	return report.ScriptResult{
+		Phase:    scriptResult.Phase,
+		ExitCode: scriptResult.ExitCode,
+		Stdout:   outputTail(scriptResult.Stdout),
+		Stderr:   outputTail(scriptResult.Stderr),
 	}
-
-	// Log results
-	gorillalog.Debug("Command Error:", err)
-	gorillalog.Debug("stdout:", outStr)
-	gorillalog.Debug("stderr:", errStr)
-
-	return cmdSuccess, err
 }
 
 var (
@@ -378,73 +374,192 @@ var (
 	uninstallItemFunc = uninstallItem
 )
 
+// exitCode returns the exit code of a command error, 0 for success, or -1 if the command did not run
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	// Any error with an exit code, like *exec.ExitError
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// outputTail returns the last `outputTailLines` lines of command output
+func outputTail(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > outputTailLines {
+		lines = lines[len(lines)-outputTailLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// finish completes an item result with its outcome, error, and duration.
+// A failure caused by a command timing out is recorded as timed out.
+func finish(result report.ItemResult, start time.Time, outcome string, err error) report.ItemResult {
+	if outcome == report.OutcomeFailed && errors.Is(err, command.ErrTimeout) {
+		outcome = report.OutcomeTimedOut
+	}
+	result.Outcome = outcome
+	result.DurationSeconds = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
 // Install determines if action needs to be taken on a item and then
-// calls the appropriate function to install or uninstall
-func Install(item catalog.Item, installerType, urlPackages, cachePath string, checkOnly bool) string {
+// calls the appropriate function to install or uninstall.
+// The result is recorded in `rpt`, which may be nil. The error is returned when the item failed.
+func Install(item catalog.Item, installerType, urlPackages, cachePath string, checkOnly bool, rpt *report.RunReport) (string, error) {
+	start := time.Now()
+	var actionErr error
+	result := report.ItemResult{
+		Name:        item.Name,
+		DisplayName: item.DisplayName,
+		Version:     item.Version,
+		Action:      installerType,
+	}
+
 	// Check the status and determine if any action is needed for this item
-	actionNeeded, err := statusCheckStatus(item, installerType, cachePath)
+	checkResult, err := statusCheck(item, installerType, cachePath)
+	result.CheckMethod = checkResult.Method
+	if checkResult.Script != nil {
+		result.Scripts = append(result.Scripts, scriptReport(*checkResult.Script))
+	}
 	if err != nil {
 		msg := fmt.Sprint("Unable to check status: ", err)
 		gorillalog.Warn(msg)
-		return msg
+		rpt.Add(finish(result, start, report.OutcomeFailed, err))
+		return msg, err
 	}
 
 	// If no action is needed, return
-	if !actionNeeded {
-		return "Item not needed"
+	if !checkResult.ActionNeeded {
+		rpt.Add(finish(result, start, report.OutcomeNotNeeded, nil))
+		return "Item not needed", nil
 	}
 
 	// Install or uninstall the item
 	if installerType == "install" || installerType == "update" {
 		// Check if checkonly mode is enabled
 		if checkOnly {
-			report.InstalledItems = append(report.InstalledItems, item)
+			rpt.Add(finish(result, start, report.OutcomeCheckOnly, nil))
 			gorillalog.Info("[CHECK ONLY] Skipping actions for", item.DisplayName)
 			// Check only mode doesn't perform any action, return
-			return "Check only enabled"
+			return "Check only enabled", nil
 		} else {
 			// Compile the item's URL
 			itemURL := urlPackages + item.Installer.Location
+			// Do not act while a reboot or the item's blocking apps are in the way
+			if outcome, msg, err := notReady(item); err != nil {
+				gorillalog.Warn("Skipping", item.DisplayName, err)
+				rpt.Add(finish(result, start, outcome, err))
+				return msg, nil
+			}
+
 			// Run PreInstall_Script if needed
 			if item.PreScript != "" {
 				gorillalog.Info("Running Pre-Install script for", item.DisplayName)
-				preScriptSuccess, err := preinstallScript(item, cachePath)
-				if !preScriptSuccess {
+				if err := runScript(item, script.PhasePreinstall, item.PreScript, installerType, cachePath, &result); err != nil {
+					err = fmt.Errorf("preinstall script failed: %w", err)
+					rpt.Add(finish(result, start, report.OutcomeFailed, err))
 					gorillalog.Error("Pre-Install script error:", err)
-					return "PreInstall-Script error"
+					return "PreInstall-Script error", err
 				}
 			}
 
-			// Run the installer
-			installItemFunc(item, itemURL, cachePath)
+			// Run the installer, or the script of a script only item
+			var installerOut string
+			if item.Installer.Type == "nopkg" {
+				installerOut, actionErr = runNopkg(item, item.Installer, script.PhaseInstall, installerType, cachePath, &result)
+			} else {
+				installerOut, actionErr = installItemFunc(item, itemURL, cachePath)
+			}
+			result.ExitCode = exitCode(actionErr)
+			result.OutputTail = outputTail(installerOut)
+			result.RebootRequired, actionErr = checkExit(item, item.Installer, actionErr)
+			if result.RebootRequired {
+				requireReboot(item)
+			}
 
-			// Run PostInstall_Script if needed
-			if item.PostScript != "" {
+			// Run PostInstall_Script if needed, unless the installer failed
+			if actionErr == nil && item.PostScript != "" {
 				gorillalog.Info("Running Post-Install script for", item.DisplayName)
-				postScriptSuccess, err := postinstallScript(item, cachePath)
-				if !postScriptSuccess {
+				if err := runScript(item, script.PhasePostinstall, item.PostScript, installerType, cachePath, &result); err != nil {
+					err = fmt.Errorf("postinstall script failed: %w", err)
+					rpt.Add(finish(result, start, report.OutcomeFailed, err))
 					gorillalog.Error("Post-Install script error:", err)
-					return "PostInstall-Script error"
+					return "PostInstall-Script error", err
 				}
 			}
 		}
 	} else if installerType == "uninstall" {
 		if checkOnly {
-			report.InstalledItems = append(report.InstalledItems, item)
+			rpt.Add(finish(result, start, report.OutcomeCheckOnly, nil))
 			gorillalog.Info("[CHECK ONLY] Skipping actions for", item.DisplayName)
 			// Check only mode doesn't perform any action, return
-			return "Check only enabled"
+			return "Check only enabled", nil
 		} else {
 			// Compile the item's URL
 			itemURL := urlPackages + item.Uninstaller.Location
-			// Run the installer
-			uninstallItemFunc(item, itemURL, cachePath)
+			// Do not act while a reboot or the item's blocking apps are in the way
+			if outcome, msg, err := notReady(item); err != nil {
+				gorillalog.Warn("Skipping", item.DisplayName, err)
+				rpt.Add(finish(result, start, outcome, err))
+				return msg, nil
+			}
+
+			// Run PreUninstall_Script if needed
+			if item.PreUninstallScript != "" {
+				gorillalog.Info("Running Pre-Uninstall script for", item.DisplayName)
+				if err := runScript(item, script.PhasePreuninstall, item.PreUninstallScript, installerType, cachePath, &result); err != nil {
+					err = fmt.Errorf("preuninstall script failed: %w", err)
+					rpt.Add(finish(result, start, report.OutcomeFailed, err))
+					gorillalog.Error("Pre-Uninstall script error:", err)
+					return "PreUninstall-Script error", err
+				}
+			}
+
+			// Run the uninstaller, or the script of a script only item
+			var uninstallerOut string
+			if item.Uninstaller.Type == "nopkg" {
+				uninstallerOut, actionErr = runNopkg(item, item.Uninstaller, script.PhaseUninstall, installerType, cachePath, &result)
+			} else {
+				uninstallerOut, actionErr = uninstallItemFunc(item, itemURL, cachePath)
+			}
+			result.ExitCode = exitCode(actionErr)
+			result.OutputTail = outputTail(uninstallerOut)
+			result.RebootRequired, actionErr = checkExit(item, item.Uninstaller, actionErr)
+			if result.RebootRequired {
+				requireReboot(item)
+			}
+
+			// Run PostUninstall_Script if needed, unless the uninstaller failed
+			if actionErr == nil && item.PostUninstallScript != "" {
+				gorillalog.Info("Running Post-Uninstall script for", item.DisplayName)
+				if err := runScript(item, script.PhasePostuninstall, item.PostUninstallScript, installerType, cachePath, &result); err != nil {
+					err = fmt.Errorf("postuninstall script failed: %w", err)
+					rpt.Add(finish(result, start, report.OutcomeFailed, err))
+					gorillalog.Error("Post-Uninstall script error:", err)
+					return "PostUninstall-Script error", err
+				}
+			}
 		}
 	} else {
 		gorillalog.Warn("Unsupported item type", item.DisplayName, installerType)
-		return "Unsupported item type"
+		err := fmt.Errorf("unsupported item type: %s", installerType)
+		rpt.Add(finish(result, start, report.OutcomeFailed, err))
+		return "Unsupported item type", err
 
 	}
 
-	return ""
+	if actionErr != nil {
+		rpt.Add(finish(result, start, report.OutcomeFailed, actionErr))
+		return "", actionErr
+	}
+	rpt.Add(finish(result, start, report.OutcomeSucceeded, nil))
+	return "", nil
 }
@@ -1,30 +1,35 @@
 package installer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/1dustindavis/gorilla/pkg/catalog"
 	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/reboot"
 	"github.com/1dustindavis/gorilla/pkg/report"
+	"github.com/1dustindavis/gorilla/pkg/status"
 )
 
 // A lot of ideas taken from https://npf.io/2015/06/testing-exec-command/
 
 var (
 	// store original data to restore after each test
-	origExec            = execCommand
-	origCheckStatus     = statusCheckStatus
-	origReportInstalled = report.InstalledItems
-	origInstallItemFunc = installItemFunc
-	origRunCommand      = runCommand
+	origExec              = execCommand
+	origCheckStatus       = statusCheck
+	origInstallItemFunc   = installItemFunc
+	origUninstallItemFunc = uninstallItemFunc
+	origRunCommand        = runCommand
 
 	// These tore the URL that `Install` generates during testing
 	installItemURL   string
@@ -115,7 +120,7 @@ func fakeExecCommand(command string, args ...string) *exec.Cmd {
 }
 
 // fakeRunCommand just returns a string and error interface
-func fakeRunCommand(command string, arguments []string) (string, error) {
+func fakeRunCommand(command string, arguments []string, timeout time.Duration) (string, error) {
 	cmdOutput := "This is a fake test command return"
 	var err error
 	if msiItem.DisplayName == statusActionNoError {
@@ -137,29 +142,34 @@ func TestHelperProcess(t *testing.T) {
 	os.Exit(0)
 }
 
-func fakeCheckStatus(catalogItem catalog.Item, installType string, cachePath string) (install bool, checkErr error) {
+// cachedPackage returns where a test package is stored in the content addressed cache
+func cachedPackage(pkg catalog.InstallerItem) string {
+	return filepath.Join("testdata", "cas", "sha256", pkg.Hash, path.Base(pkg.Location))
+}
+
+func fakeCheckStatus(catalogItem catalog.Item, installType string, cachePath string) (status.Result, error) {
 	// Catch special names used in tests
 	if catalogItem.DisplayName == statusActionNoError {
 		gorillalog.Warn("Running Development Tests!")
 		gorillalog.Warn(catalogItem.DisplayName)
-		return true, nil
+		return status.Result{ActionNeeded: true, Method: status.MethodScript}, nil
 	} else if catalogItem.DisplayName == statusNoActionNoError {
 		gorillalog.Warn("Running Development Tests!")
 		gorillalog.Warn(catalogItem.DisplayName)
-		return false, nil
+		return status.Result{Method: status.MethodScript}, nil
 	} else if catalogItem.DisplayName == statusActionError {
 		gorillalog.Warn("Running Development Tests!")
 		gorillalog.Warn(catalogItem.DisplayName)
-		return true, fmt.Errorf("testing %v", catalogItem.DisplayName)
+		return status.Result{ActionNeeded: true, Method: status.MethodScript}, fmt.Errorf("testing %v", catalogItem.DisplayName)
 	} else if catalogItem.DisplayName == statusNoActionError {
 		gorillalog.Warn("Running Development Tests!")
 		gorillalog.Warn(catalogItem.DisplayName)
-		return false, fmt.Errorf("testing %v", catalogItem.DisplayName)
+		return status.Result{Method: status.MethodScript}, fmt.Errorf("testing %v", catalogItem.DisplayName)
 	}
 
 	fmt.Println(catalogItem.DisplayName)
 	fmt.Println(installType)
-	return false, nil
+	return status.Result{Method: status.MethodScript}, nil
 }
 
 // TestRunCommand verifies that the command and it's arguments are processed correctly
@@ -174,7 +184,7 @@ func TestRunCommand(t *testing.T) {
 	testCmd := append([]string{testCommand}, testArgs...)
 	expectedCmd := fmt.Sprint(testCmd)
 
-	actualCmd, _ := runCommand(testCommand, testArgs)
+	actualCmd, _ := runCommand(testCommand, testArgs, 0)
 
 	// Compare the result with our expectations
 	structsMatch := reflect.DeepEqual(expectedCmd, actualCmd)
@@ -189,15 +199,14 @@ func TestRunCommand(t *testing.T) {
 func TestInstallItem(t *testing.T) {
 	// Override execCommand and checkStatus with our fake versions
 	execCommand = fakeExecCommand
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
 		execCommand = origExec
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Set shared testing variables
 	cachePath := "testdata/"
-	pkgCache := "testdata/packages/"
 	urlPackages := "https://example.com/"
 
 	//
@@ -208,11 +217,11 @@ func TestInstallItem(t *testing.T) {
 	nupkgURL := urlPackages + nupkgPath
 
 	// Run Install
-	actualNupkg := installItem(nupkgItem, nupkgURL, cachePath)
+	actualNupkg, _ := installItem(nupkgItem, nupkgURL, cachePath)
 
 	// Check the result
 	nupkgCmd := filepath.Join(os.Getenv("ProgramData"), "chocolatey/bin/choco.exe")
-	nupkgFile := filepath.Join(pkgCache, nupkgPath)
+	nupkgFile := cachedPackage(nupkgItem.Installer)
 	nupkgDir := filepath.Dir(nupkgFile)
 	nupkgID := fmt.Sprintf("[%s list --version=1.2.3 --id-only -r -s %s]", nupkgCmd, nupkgDir)
 	expectedNupkg := fmt.Sprintf("[%s install %s -s %s --version=1.2.3 -f -y -r]", nupkgCmd, nupkgID, nupkgDir)
@@ -228,11 +237,11 @@ func TestInstallItem(t *testing.T) {
 	msiURL := urlPackages + msiPath
 
 	// Run Install
-	actualMsi := installItem(msiItem, msiURL, cachePath)
+	actualMsi, _ := installItem(msiItem, msiURL, cachePath)
 
 	// Check the result
 	msiCmd := filepath.Join(os.Getenv("WINDIR"), "system32/msiexec.exe")
-	msiFile := filepath.Join(pkgCache, msiPath)
+	msiFile := cachedPackage(msiItem.Installer)
 	expectedMsi := "[" + msiCmd + " /i " + msiFile + " /qn /norestart /L=1033 /S]"
 	if have, want := actualMsi, expectedMsi; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
@@ -246,10 +255,10 @@ func TestInstallItem(t *testing.T) {
 	exeURL := urlPackages + exePath
 
 	// Run Install
-	actualExe := installItem(exeItem, exeURL, cachePath)
+	actualExe, _ := installItem(exeItem, exeURL, cachePath)
 
 	// Check the result
-	exeFile := filepath.Join(pkgCache, exePath)
+	exeFile := cachedPackage(exeItem.Installer)
 	expectedExe := "[" + exeFile + " /L=1033 /S]"
 	if have, want := actualExe, expectedExe; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
@@ -263,11 +272,11 @@ func TestInstallItem(t *testing.T) {
 	ps1URL := urlPackages + ps1Path
 
 	// Run Install
-	actualPs1 := installItem(ps1Item, ps1URL, cachePath)
+	actualPs1, _ := installItem(ps1Item, ps1URL, cachePath)
 
 	// Check the result
 	ps1Cmd := filepath.Join(os.Getenv("WINDIR"), "system32/WindowsPowershell/v1.0/powershell.exe")
-	ps1File := filepath.Join(pkgCache, ps1Path)
+	ps1File := cachedPackage(ps1Item.Installer)
 	expectedPs1 := "[" + ps1Cmd + " -NoProfile -NoLogo -NonInteractive -ExecutionPolicy Bypass -File " + ps1File + "]"
 	if have, want := actualPs1, expectedPs1; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
@@ -278,40 +287,46 @@ func TestInstallItem(t *testing.T) {
 // TestInstallStatusError verifies that Install returns if status check fails
 func TestInstallStatusError(t *testing.T) {
 	// Override checkStatus with our fake version
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Run the msi installer with this status bypass to trigger an error
 	msiItem.DisplayName = statusActionError
 	// Run Install
-	actualOutput := Install(msiItem, "install", "https://example.com", "testdata/", checkOnlyMode)
+	actualOutput, err := Install(msiItem, "install", "https://example.com", "testdata/", checkOnlyMode, nil)
 	// Check the result
 	expectedOutput := "Unable to check status: testing _gorilla_dev_action_error_"
 	if have, want := actualOutput, expectedOutput; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
 	}
+	if err == nil {
+		t.Errorf("Expected the status error to be returned")
+	}
 
 }
 
 // TestInstallStatusFalse verifies that Install returns if status check is false
 func TestInstallStatusFalse(t *testing.T) {
 	// Override checkStatus with our fake version
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Run the msi installer with this status bypass to make status return false
 	msiItem.DisplayName = statusNoActionNoError
 	// Run Install
-	actualOutput := Install(msiItem, "install", "https://example.com/", "testdata/", checkOnlyMode)
+	actualOutput, err := Install(msiItem, "install", "https://example.com/", "testdata/", checkOnlyMode, nil)
 	// Check the result
 	expectedOutput := "Item not needed"
 	if have, want := actualOutput, expectedOutput; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
 	}
+	if err != nil {
+		t.Errorf("Expected no error: %v", err)
+	}
 
 }
 
@@ -320,16 +335,15 @@ func TestInstallStatusFalse(t *testing.T) {
 func TestUninstallItem(t *testing.T) {
 	// Override execCommand and checkStatus with our fake versions
 	execCommand = fakeExecCommand
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	download.SetConfig(downloadCfg)
 	defer func() {
 		execCommand = origExec
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Set shared testing variables
 	cachePath := "testdata/"
-	pkgCache := "testdata/packages/"
 	urlPackages := "https://example.com/"
 
 	//
@@ -339,10 +353,10 @@ func TestUninstallItem(t *testing.T) {
 	nupkgPath := "chef-client/chef-client-14.3.37-1-x64uninst.nupkg"
 	nupkgURL := urlPackages + nupkgPath
 	// Run Uninstall
-	actualNupkg := uninstallItem(nupkgItem, nupkgURL, cachePath)
+	actualNupkg, _ := uninstallItem(nupkgItem, nupkgURL, cachePath)
 	// Check the result
 	nupkgCmd := filepath.Join(os.Getenv("ProgramData"), "chocolatey/bin/choco.exe")
-	nupkgFile := filepath.Join(pkgCache, nupkgPath)
+	nupkgFile := cachedPackage(nupkgItem.Uninstaller)
 	nupkgDir := filepath.Dir(nupkgFile)
 	nupkgID := fmt.Sprintf("[%s list --version=1.2.3 --id-only -r -s %s]", nupkgCmd, nupkgDir)
 	expectedNupkg := fmt.Sprintf("[%s uninstall %s -s %s --version=1.2.3 -f -y -r]", nupkgCmd, nupkgID, nupkgDir)
@@ -355,10 +369,10 @@ func TestUninstallItem(t *testing.T) {
 	//
 	msiItem.DisplayName = statusNoActionNoError
 	// Run Uninstall
-	actualMsi := uninstallItem(msiItem, urlPackages, cachePath)
+	actualMsi, _ := uninstallItem(msiItem, urlPackages, cachePath)
 	// Check the result
 	msiCmd := filepath.Join(os.Getenv("WINDIR"), "system32/msiexec.exe")
-	msiPath := filepath.Clean("testdata/packages/chef-client/chef-client-14.3.37-1-x64uninst.msi")
+	msiPath := cachedPackage(msiItem.Uninstaller)
 	expectedMsi := "[" + msiCmd + " /x " + msiPath + " /qn /norestart]"
 	if have, want := actualMsi, expectedMsi; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
@@ -369,9 +383,9 @@ func TestUninstallItem(t *testing.T) {
 	//
 	exeItem.DisplayName = statusNoActionNoError
 	// Run Uninstall
-	actualExe := uninstallItem(exeItem, urlPackages, cachePath)
+	actualExe, _ := uninstallItem(exeItem, urlPackages, cachePath)
 	// Check the result
-	exePath := filepath.Clean("testdata/packages/chef-client/chef-client-14.3.37-1-x64uninst.exe")
+	exePath := cachedPackage(exeItem.Uninstaller)
 	expectedExe := "[" + exePath + " /U=1033 /S]"
 	if have, want := actualExe, expectedExe; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
@@ -382,10 +396,10 @@ func TestUninstallItem(t *testing.T) {
 	//
 	ps1Item.DisplayName = statusNoActionNoError
 	// Run Uninstall
-	actualPs1 := uninstallItem(ps1Item, urlPackages, cachePath)
+	actualPs1, _ := uninstallItem(ps1Item, urlPackages, cachePath)
 	// Check the result
 	ps1Cmd := filepath.Join(os.Getenv("WINDIR"), "system32/WindowsPowershell/v1.0/powershell.exe")
-	ps1Path := filepath.Clean("testdata/packages/chef-client/chef-client-14.3.37-1-x64uninst.ps1")
+	ps1Path := cachedPackage(ps1Item.Uninstaller)
 	expectedPs1 := "[" + ps1Cmd + " -NoProfile -NoLogo -NonInteractive -ExecutionPolicy Bypass -File " + ps1Path + "]"
 	if have, want := actualPs1, expectedPs1; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
@@ -402,19 +416,18 @@ func TestInstallItemNupkgWithExplicitPackageID(t *testing.T) {
 	}()
 
 	cachePath := "testdata/"
-	pkgCache := "testdata/packages/"
 	urlPackages := "https://example.com/"
 
 	nupkgPath := "chef-client/chef-client-14.3.37-1-x64.nupkg"
 	nupkgURL := urlPackages + nupkgPath
-	nupkgFile := filepath.Join(pkgCache, nupkgPath)
+	nupkgFile := cachedPackage(nupkgItem.Installer)
 	nupkgDir := filepath.Dir(nupkgFile)
 
 	item := nupkgItem
 	item.DisplayName = statusActionNoError
 	item.Installer.PackageID = "chef-client"
 
-	actual := installItem(item, nupkgURL, cachePath)
+	actual, _ := installItem(item, nupkgURL, cachePath)
 	expected := fmt.Sprintf("[%s install chef-client -s %s --version=1.2.3 -f -y -r]", commandNupkg, nupkgDir)
 
 	if have, want := actual, expected; have != want {
@@ -423,7 +436,7 @@ func TestInstallItemNupkgWithExplicitPackageID(t *testing.T) {
 }
 
 func TestInstallItemNupkgAmbiguousPackageID(t *testing.T) {
-	runCommand = func(command string, arguments []string) (string, error) {
+	runCommand = func(command string, arguments []string, timeout time.Duration) (string, error) {
 		if len(arguments) > 0 && arguments[0] == "list" {
 			return "chef-client\nchef-client-alt", nil
 		}
@@ -441,7 +454,7 @@ func TestInstallItemNupkgAmbiguousPackageID(t *testing.T) {
 	item.DisplayName = "Ambiguous Package"
 	item.Installer.PackageID = ""
 
-	actual := installItem(item, nupkgURL, cachePath)
+	actual, _ := installItem(item, nupkgURL, cachePath)
 	if !strings.Contains(actual, "Unable to determine nupkg id") || !strings.Contains(actual, "multiple package ids were found") {
 		t.Fatalf("expected ambiguity error message, got: %s", actual)
 	}
@@ -456,19 +469,18 @@ func TestUninstallItemNupkgWithExplicitPackageID(t *testing.T) {
 	}()
 
 	cachePath := "testdata/"
-	pkgCache := "testdata/packages/"
 	urlPackages := "https://example.com/"
 
 	nupkgPath := "chef-client/chef-client-14.3.37-1-x64uninst.nupkg"
 	nupkgURL := urlPackages + nupkgPath
-	nupkgFile := filepath.Join(pkgCache, nupkgPath)
+	nupkgFile := cachedPackage(nupkgItem.Uninstaller)
 	nupkgDir := filepath.Dir(nupkgFile)
 
 	item := nupkgItem
 	item.DisplayName = statusNoActionNoError
 	item.Uninstaller.PackageID = "chef-client"
 
-	actual := uninstallItem(item, nupkgURL, cachePath)
+	actual, _ := uninstallItem(item, nupkgURL, cachePath)
 	expected := fmt.Sprintf("[%s uninstall chef-client -s %s --version=1.2.3 -f -y -r]", commandNupkg, nupkgDir)
 
 	if have, want := actual, expected; have != want {
@@ -477,7 +489,7 @@ func TestUninstallItemNupkgWithExplicitPackageID(t *testing.T) {
 }
 
 func TestUninstallItemNupkgAmbiguousPackageID(t *testing.T) {
-	runCommand = func(command string, arguments []string) (string, error) {
+	runCommand = func(command string, arguments []string, timeout time.Duration) (string, error) {
 		if len(arguments) > 0 && arguments[0] == "list" {
 			return "chef-client\nchef-client-alt", nil
 		}
@@ -495,7 +507,7 @@ func TestUninstallItemNupkgAmbiguousPackageID(t *testing.T) {
 	item.DisplayName = "Ambiguous Uninstall Package"
 	item.Uninstaller.PackageID = ""
 
-	actual := uninstallItem(item, nupkgURL, cachePath)
+	actual, _ := uninstallItem(item, nupkgURL, cachePath)
 	if !strings.Contains(actual, "Unable to determine nupkg id") || !strings.Contains(actual, "multiple package ids were found") {
 		t.Fatalf("expected ambiguity error message, got: %s", actual)
 	}
@@ -504,121 +516,154 @@ func TestUninstallItemNupkgAmbiguousPackageID(t *testing.T) {
 // TestUninstallStatusError verifies that Uninstall returns if status check fails
 func TestUninstallStatusError(t *testing.T) {
 	// Override checkStatus with our fake version
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Run the msi uninstaller with this status bypass to trigger an error
 	msiItem.DisplayName = statusNoActionError
 	// Run Uninstall
-	actualOutput := Install(msiItem, "uninstall", "https://example.com", "testdata/", checkOnlyMode)
+	actualOutput, err := Install(msiItem, "uninstall", "https://example.com", "testdata/", checkOnlyMode, nil)
 	// Check the result
 	expectedOutput := "Unable to check status: testing _gorilla_dev_noaction_error_"
 	if have, want := actualOutput, expectedOutput; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
 	}
+	if err == nil {
+		t.Errorf("Expected the status error to be returned")
+	}
 
 }
 
 // TestUninstallStatusTrue verifies that Uninstall returns if status check is true
 func TestUninstallStatusTrue(t *testing.T) {
 	// Override checkStatus with our fake version
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Run the msi uninstaller with this status bypass to make status return true
 	msiItem.DisplayName = statusNoActionNoError
 	// Run Uninstall
-	actualOutput := Install(msiItem, "uninstall", "https://example.com", "testdata/", checkOnlyMode)
+	actualOutput, err := Install(msiItem, "uninstall", "https://example.com", "testdata/", checkOnlyMode, nil)
 	// Check the result
 	expectedOutput := "Item not needed"
 	if have, want := actualOutput, expectedOutput; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
 	}
+	if err != nil {
+		t.Errorf("Expected no error: %v", err)
+	}
 
 }
 
 // TestUpdateStatusError verifies that Update returns if status check fails
 func TestUpdateStatusError(t *testing.T) {
 	// Override checkStatus with our fake version
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Run the msi installer with this status bypass to trigger an error
 	msiItem.DisplayName = statusActionError
 	// Run Update
-	actualOutput := Install(msiItem, "update", "https://example.com", "testdata/", checkOnlyMode)
+	actualOutput, err := Install(msiItem, "update", "https://example.com", "testdata/", checkOnlyMode, nil)
 	// Check the result
 	expectedOutput := "Unable to check status: testing _gorilla_dev_action_error_"
 	if have, want := actualOutput, expectedOutput; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
 	}
+	if err == nil {
+		t.Errorf("Expected the status error to be returned")
+	}
 
 }
 
 // TestUpdateStatusFalse verifies that Update returns if status check is false
 func TestUpdateStatusFalse(t *testing.T) {
 	// Override checkStatus with our fake version
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 	}()
 
 	// Run the msi installer with this status bypass to make status return dalse
 	msiItem.DisplayName = statusNoActionNoError
 	// Run Update
-	actualOutput := Install(msiItem, "update", "https://example.com", "testdata/", checkOnlyMode)
+	actualOutput, err := Install(msiItem, "update", "https://example.com", "testdata/", checkOnlyMode, nil)
 	// Check the result
 	expectedOutput := "Item not needed"
 	if have, want := actualOutput, expectedOutput; have != want {
 		t.Errorf("\n-----\nhave\n%s\nwant\n%s\n-----", have, want)
 	}
+	if err != nil {
+		t.Errorf("Expected no error: %v", err)
+	}
 
 }
 
-// TestInstallReport verifies that an installed item is added to the report
+// TestInstallReport verifies that the result of an install is added to the report
 func TestInstallReport(t *testing.T) {
-	// Override execCommand with our fake version
-	execCommand = fakeExecCommand
-	// Override the report.InstalledItems to be empty
-	report.InstalledItems = []interface{}{}
+	// Override the status check and command with our fake versions
+	statusCheck = fakeCheckStatus
+	runCommand = fakeRunCommand
+	download.SetConfig(downloadCfg)
 	defer func() {
-		execCommand = origExec
-		report.InstalledItems = origReportInstalled
+		statusCheck = origCheckStatus
+		runCommand = origRunCommand
 	}()
 
-	// Run the installer
-	installItem(msiItem, "https://example.com", "testdata/")
+	rpt := &report.RunReport{}
+
+	// Run a successful install
+	msiItem.DisplayName = statusActionNoError
+	Install(msiItem, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+
+	// Run an item that is not needed
+	exeItem.DisplayName = statusNoActionNoError
+	Install(exeItem, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+
+	// Run a failed install
+	msiItem.DisplayName = statusActionError
+	statusCheck = func(catalogItem catalog.Item, installType string, cachePath string) (status.Result, error) {
+		return status.Result{ActionNeeded: true, Method: status.MethodRegistry}, nil
+	}
+	Install(msiItem, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
 
 	// Check the result
-	expectedReport := []interface{}{msiItem}
+	expectedReport := []report.ItemResult{
+		{DisplayName: statusActionNoError, Version: "1.2.3", Action: "install", Outcome: report.OutcomeSucceeded, OutputTail: "This is a fake test command return", CheckMethod: status.MethodScript},
+		{DisplayName: statusNoActionNoError, Action: "install", Outcome: report.OutcomeNotNeeded, CheckMethod: status.MethodScript},
+		{DisplayName: statusActionError, Version: "1.2.3", Action: "install", Outcome: report.OutcomeFailed, ExitCode: -1, Error: "Deliberate test error has occurred!!", OutputTail: "This is a fake test command return", CheckMethod: status.MethodRegistry},
+	}
 
-	// Compare the result with our expectations
-	structsMatch := reflect.DeepEqual(expectedReport, report.InstalledItems)
+	// Durations vary, so clear them before comparing
+	for i := range rpt.Items {
+		rpt.Items[i].DurationSeconds = 0
+	}
 
-	if !structsMatch {
-		t.Errorf("\nExpected: %#v\nReceived: %#v", expectedReport, report.InstalledItems)
+	// Compare the result with our expectations
+	if !reflect.DeepEqual(expectedReport, rpt.Items) {
+		t.Errorf("\nExpected: %#v\nReceived: %#v", expectedReport, rpt.Items)
 	}
 
 }
 
-func fakeInstallItem(item catalog.Item, itemURL, cachePath string) string {
+func fakeInstallItem(item catalog.Item, itemURL, cachePath string) (string, error) {
 	installItemURL = itemURL
-	return ""
+	return "", nil
 }
 
 // TestInstallURL validates that the url for an installer is properly generated
 func TestInstallURL(t *testing.T) {
 	// Override checkStatus and installItemFunc with our fake versions
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	installItemFunc = fakeInstallItem
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 		installItemFunc = origInstallItemFunc
 	}()
 
@@ -629,7 +674,7 @@ func TestInstallURL(t *testing.T) {
 	msiItem.DisplayName = statusActionNoError
 
 	// Run Install
-	Install(msiItem, "install", "https://example.com/", "testdata/", checkOnlyMode)
+	Install(msiItem, "install", "https://example.com/", "testdata/", checkOnlyMode, nil)
 
 	// Check the result
 	expectedURL := "https://example.com/packages/chef-client/chef-client-14.3.37-1-x64.msi"
@@ -639,18 +684,18 @@ func TestInstallURL(t *testing.T) {
 	}
 }
 
-func fakeUninstallItem(item catalog.Item, itemURL, cachePath string) string {
+func fakeUninstallItem(item catalog.Item, itemURL, cachePath string) (string, error) {
 	uninstallItemURL = itemURL
-	return ""
+	return "", nil
 }
 
 // TestUninstallURL validates that the url for an installer is properly generated
 func TestUninstallURL(t *testing.T) {
 	// Override checkStatus and installItemFunc with our fake versions
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	uninstallItemFunc = fakeUninstallItem
 	defer func() {
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 		installItemFunc = origInstallItemFunc
 	}()
 
@@ -661,7 +706,7 @@ func TestUninstallURL(t *testing.T) {
 	msiItem.DisplayName = statusActionNoError
 
 	// Run Install
-	Install(msiItem, "uninstall", "https://example.com/", "testdata/", checkOnlyMode)
+	Install(msiItem, "uninstall", "https://example.com/", "testdata/", checkOnlyMode, nil)
 
 	// Check the result
 	expectedURL := "https://example.com/packages/chef-client/chef-client-14.3.37-1-x64.msi"
@@ -695,7 +740,7 @@ func Example_runCommand() {
 	testArgs := []string{"arg1", "arg2"}
 
 	// Run the function
-	runCommand(testCmd, testArgs)
+	runCommand(testCmd, testArgs, 0)
 
 	// Output:
 	// command: Command Test! [arg1 arg2]
@@ -708,12 +753,12 @@ func Example_runCommand() {
 func Example_installItemSuccess() {
 	// Override execCommand and checkStatus with our fake versions
 	execCommand = fakeExecCommand
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	runCommand = fakeRunCommand
 	download.SetConfig(downloadCfg)
 	defer func() {
 		execCommand = origExec
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 		runCommand = origRunCommand
 	}()
 
@@ -740,12 +785,12 @@ func Example_installItemSuccess() {
 func Example_installItemFailure() {
 	// Override execCommand and checkStatus with our fake versions
 	execCommand = fakeExecCommand
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	runCommand = fakeRunCommand
 	download.SetConfig(downloadCfg)
 	defer func() {
 		execCommand = origExec
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 		runCommand = origRunCommand
 	}()
 
@@ -772,12 +817,12 @@ func Example_installItemFailure() {
 func Example_uninstallItemSuccess() {
 	// Override execCommand and checkStatus with our fake versions
 	execCommand = fakeExecCommand
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	runCommand = fakeRunCommand
 	download.SetConfig(downloadCfg)
 	defer func() {
 		execCommand = origExec
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 		runCommand = origRunCommand
 	}()
 
@@ -802,12 +847,12 @@ func Example_uninstallItemSuccess() {
 func Example_uninstallItemFailure() {
 	// Override execCommand and checkStatus with our fake versions
 	execCommand = fakeExecCommand
-	statusCheckStatus = fakeCheckStatus
+	statusCheck = fakeCheckStatus
 	runCommand = fakeRunCommand
 	download.SetConfig(downloadCfg)
 	defer func() {
 		execCommand = origExec
-		statusCheckStatus = origCheckStatus
+		statusCheck = origCheckStatus
 		runCommand = origRunCommand
 	}()
 
@@ -828,3 +873,186 @@ func Example_uninstallItemFailure() {
 	// _gorilla_dev_action_error_ 1.2.3 Uninstallation FAILED
 
 }
+
+// TestInstallScripts verifies the output of an item's scripts is recorded in the report
+func TestInstallScripts(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	statusCheck = fakeCheckStatus
+	installItemFunc = fakeInstallItem
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+
+	rpt := &report.RunReport{}
+	item := msiItem
+	item.DisplayName = statusActionNoError
+	item.ScriptInterpreter = "sh"
+	item.PreScript = `echo "before $GORILLA_ACTION"`
+	item.PostScript = `echo "after $GORILLA_ACTION"`
+	Install(item, "install", "https://example.com/", t.TempDir(), checkOnlyMode, rpt)
+
+	expected := []report.ScriptResult{
+		{Phase: "preinstall", Stdout: "before install"},
+		{Phase: "postinstall", Stdout: "after install"},
+	}
+	if actual := rpt.Items[0].Scripts; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nExpected: %#v\nActual:   %#v", expected, actual)
+	}
+}
+
+// TestInstallNopkg verifies a script only item runs its scripts in place of a package
+func TestInstallNopkg(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	statusCheck = fakeCheckStatus
+	installItemFunc = installItem
+	uninstallItemFunc = uninstallItem
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+		uninstallItemFunc = origUninstallItemFunc
+	}()
+
+	rpt := &report.RunReport{}
+	item := catalog.Item{
+		Name:                "Telemetry",
+		DisplayName:         statusActionNoError,
+		ScriptInterpreter:   "sh",
+		Installer:           catalog.InstallerItem{Type: "nopkg", Script: `echo "$GORILLA_ACTION $GORILLA_ITEM_NAME"; echo warning >&2`},
+		Uninstaller:         catalog.InstallerItem{Type: "nopkg", Script: "exit 7", RebootExitCodes: []int{7}},
+		PreUninstallScript:  "echo before",
+		PostUninstallScript: "echo after",
+	}
+	cachePath := t.TempDir()
+	Install(item, "update", "https://example.com/", cachePath, checkOnlyMode, rpt)
+
+	if have, want := rpt.Items[0].Outcome, report.OutcomeSucceeded; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := rpt.Items[0].OutputTail, "update Telemetry"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	expected := []report.ScriptResult{{Phase: "install", Stdout: "update Telemetry", Stderr: "warning"}}
+	if actual := rpt.Items[0].Scripts; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nExpected: %#v\nActual:   %#v", expected, actual)
+	}
+
+	// The uninstall scripts run around the uninstall script, which can require a reboot
+	origCfg := installerCfg
+	defer func() { installerCfg = origCfg }()
+	installerCfg = config.Configuration{AppDataPath: t.TempDir()}
+	Install(item, "uninstall", "https://example.com/", cachePath, checkOnlyMode, rpt)
+
+	if have, want := rpt.Items[1].Outcome, report.OutcomeSucceeded; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if !rpt.Items[1].RebootRequired {
+		t.Errorf("Expected the uninstall to require a reboot")
+	}
+	expected = []report.ScriptResult{
+		{Phase: "preuninstall", Stdout: "before"},
+		{Phase: "uninstall", ExitCode: 7},
+		{Phase: "postuninstall", Stdout: "after"},
+	}
+	if actual := rpt.Items[1].Scripts; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nExpected: %#v\nActual:   %#v", expected, actual)
+	}
+}
+
+// TestInstallScriptFailure verifies a failed script is returned and recorded instead of stopping the run
+func TestInstallScriptFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	statusCheck = fakeCheckStatus
+	installItemFunc = fakeInstallItem
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+
+	rpt := &report.RunReport{}
+	item := msiItem
+	item.DisplayName = statusActionNoError
+	item.ScriptInterpreter = "sh"
+	item.PreScript = "exit 1"
+	installItemURL = ""
+	msg, err := Install(item, "install", "https://example.com/", t.TempDir(), checkOnlyMode, rpt)
+
+	if have, want := msg, "PreInstall-Script error"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if err == nil {
+		t.Errorf("Expected the script error to be returned")
+	}
+	if installItemURL != "" {
+		t.Errorf("Expected the installer not to run")
+	}
+	if have, want := rpt.Items[0].Outcome, report.OutcomeFailed; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
+
+// TestInstallPostScriptSkipped verifies the post-install script does not run after the installer fails
+func TestInstallPostScriptSkipped(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	installerErr := errors.New("installer failed")
+	statusCheck = fakeCheckStatus
+	installItemFunc = func(item catalog.Item, itemURL, cachePath string) (string, error) {
+		return "", installerErr
+	}
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+
+	rpt := &report.RunReport{}
+	item := msiItem
+	item.DisplayName = statusActionNoError
+	item.ScriptInterpreter = "sh"
+	item.PostScript = "echo after"
+	_, err := Install(item, "install", "https://example.com/", t.TempDir(), checkOnlyMode, rpt)
+
+	if !errors.Is(err, installerErr) {
+		t.Errorf("Expected the installer error, got: %v", err)
+	}
+	if len(rpt.Items[0].Scripts) != 0 {
+		t.Errorf("Expected the post-install script not to run, have %#v", rpt.Items[0].Scripts)
+	}
+}
+
+// TestInstallRebootBeforePostScript verifies a reboot is recorded even if the post-install script fails
+func TestInstallRebootBeforePostScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	statusCheck = fakeCheckStatus
+	installItemFunc = fakeInstallItem
+	origCfg := installerCfg
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+		installerCfg = origCfg
+	}()
+	installerCfg = config.Configuration{AppDataPath: t.TempDir()}
+
+	rpt := &report.RunReport{}
+	item := msiItem
+	item.Name = "RebootTest"
+	item.DisplayName = statusActionNoError
+	item.RestartAction = RestartRequire
+	item.ScriptInterpreter = "sh"
+	item.PostScript = "exit 1"
+	if _, err := Install(item, "install", "https://example.com/", t.TempDir(), checkOnlyMode, rpt); err == nil {
+		t.Errorf("Expected the script error to be returned")
+	}
+	if !reboot.IsPending(installerCfg.AppDataPath) {
+		t.Errorf("Expected a pending reboot to be recorded")
+	}
+}
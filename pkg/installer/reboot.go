@@ -0,0 +1,92 @@
+package installer
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/reboot"
+	"github.com/1dustindavis/gorilla/pkg/report"
+)
+
+// Restart actions for a catalog item
+const (
+	RestartNone    = "none"
+	RestartRequire = "require_restart"
+)
+
+// defaultRebootExitCodes are the exit codes of each installer type that mean it succeeded, but a reboot is required.
+// 3010 is "success, reboot required" and 1641 is "success, reboot initiated".
+var defaultRebootExitCodes = map[string][]int{
+	"msi":   {3010, 1641},
+	"exe":   {3010, 1641},
+	"nupkg": {3010, 1641},
+	"ps1":   {3010},
+	"nopkg": {3010},
+}
+
+// exitCodes returns the exit codes that mean success, and those that mean success with a reboot required.
+// The catalog may replace either list for an item.
+func exitCodes(installer catalog.InstallerItem) (success, rebootCodes []int) {
+	success = []int{0}
+	if len(installer.SuccessExitCodes) > 0 {
+		success = installer.SuccessExitCodes
+	}
+	rebootCodes = defaultRebootExitCodes[installer.Type]
+	if len(installer.RebootExitCodes) > 0 {
+		rebootCodes = installer.RebootExitCodes
+	}
+	return success, rebootCodes
+}
+
+// exitResult returns true if a command error means the installer succeeded, and true if it needs a reboot
+func exitResult(installer catalog.InstallerItem, err error) (ok bool, needsReboot bool) {
+	code := exitCode(err)
+	if code == -1 {
+		// The command did not run
+		return false, false
+	}
+	success, rebootCodes := exitCodes(installer)
+	if slices.Contains(rebootCodes, code) {
+		return true, true
+	}
+	return slices.Contains(success, code), false
+}
+
+// checkExit returns nil if the exit code of `err` means success, and true if the item needs a reboot
+func checkExit(item catalog.Item, installer catalog.InstallerItem, err error) (bool, error) {
+	ok, needsReboot := exitResult(installer, err)
+	if !ok {
+		return false, err
+	}
+	return needsReboot || item.RestartAction == RestartRequire, nil
+}
+
+// rebootPending returns true if an earlier install is waiting for a reboot
+func rebootPending() bool {
+	return installerCfg.AppDataPath != "" && reboot.IsPending(installerCfg.AppDataPath)
+}
+
+// requireReboot records that an item needs a reboot to finish
+func requireReboot(item catalog.Item) {
+	gorillalog.Info(item.DisplayName, "requires a reboot")
+	if installerCfg.AppDataPath == "" {
+		return
+	}
+	if err := reboot.Require(installerCfg.AppDataPath, item.Name); err != nil {
+		gorillalog.Warn("Unable to record pending reboot for", item.DisplayName, err)
+	}
+}
+
+// notReady returns why an item can not be installed or uninstalled yet: the outcome to report,
+// a message, and the error. The error is nil if the item is ready.
+func notReady(item catalog.Item) (outcome, msg string, err error) {
+	if item.RequiresCleanBoot && rebootPending() {
+		return report.OutcomeDeferred, "Deferred until after a reboot", errors.New("requires a clean boot, and a reboot is pending")
+	}
+	if err := checkBlockingApps(item); err != nil {
+		return report.OutcomeBlocked, "Blocked by running applications", err
+	}
+	return "", "", nil
+}
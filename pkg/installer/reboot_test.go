@@ -0,0 +1,135 @@
+package installer
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/config"
+	"github.com/1dustindavis/gorilla/pkg/reboot"
+	"github.com/1dustindavis/gorilla/pkg/report"
+)
+
+// exitError is a command error with an exit code. Exit codes like 3010 do not fit in a
+// Unix exit status, so a real command can not be used.
+type exitError int
+
+func (e exitError) Error() string { return "exit status " + strconv.Itoa(int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// TestExitResult verifies the default and configured exit codes for each installer type
+func TestExitResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		installer catalog.InstallerItem
+		code      int
+		ok        bool
+		reboot    bool
+	}{
+		{name: "msi success", installer: catalog.InstallerItem{Type: "msi"}, code: 0, ok: true},
+		{name: "msi reboot required", installer: catalog.InstallerItem{Type: "msi"}, code: 3010, ok: true, reboot: true},
+		{name: "msi reboot initiated", installer: catalog.InstallerItem{Type: "msi"}, code: 1641, ok: true, reboot: true},
+		{name: "msi failure", installer: catalog.InstallerItem{Type: "msi"}, code: 1603},
+		{name: "nupkg reboot required", installer: catalog.InstallerItem{Type: "nupkg"}, code: 3010, ok: true, reboot: true},
+		{name: "ps1 reboot initiated", installer: catalog.InstallerItem{Type: "ps1"}, code: 1641},
+		{name: "exe custom success", installer: catalog.InstallerItem{Type: "exe", SuccessExitCodes: []int{0, 1}}, code: 1, ok: true},
+		{name: "exe custom reboot", installer: catalog.InstallerItem{Type: "exe", RebootExitCodes: []int{194}}, code: 194, ok: true, reboot: true},
+		{name: "exe custom reboot replaces defaults", installer: catalog.InstallerItem{Type: "exe", RebootExitCodes: []int{194}}, code: 3010},
+	}
+	for _, tt := range tests {
+		var err error
+		if tt.code != 0 {
+			err = exitError(tt.code)
+		}
+		ok, needsReboot := exitResult(tt.installer, err)
+		if ok != tt.ok || needsReboot != tt.reboot {
+			t.Errorf("%s: have ok %v reboot %v, want ok %v reboot %v", tt.name, ok, needsReboot, tt.ok, tt.reboot)
+		}
+	}
+}
+
+// TestInstallReboot verifies a reboot exit code is a success that leaves a reboot pending,
+// and that items needing a clean boot wait for it
+func TestInstallReboot(t *testing.T) {
+	origCfg := installerCfg
+	defer func() { installerCfg = origCfg }()
+	installerCfg = config.Configuration{AppDataPath: t.TempDir()}
+
+	statusCheck = fakeCheckStatus
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+	installItemFunc = func(item catalog.Item, itemURL, cachePath string) (string, error) {
+		return "", exitError(3010)
+	}
+
+	rpt := &report.RunReport{}
+	item := msiItem
+	item.Name = "ChefClient"
+	item.DisplayName = statusActionNoError
+	Install(item, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+
+	if have, want := rpt.Items[0].Outcome, report.OutcomeSucceeded; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := rpt.Items[0].ExitCode, 3010; have != want {
+		t.Errorf("have %d, want %d", have, want)
+	}
+	if !rpt.Items[0].RebootRequired {
+		t.Errorf("Expected the item to require a reboot")
+	}
+	if !reboot.IsPending(installerCfg.AppDataPath) {
+		t.Errorf("Expected a reboot to be pending")
+	}
+
+	// An item that needs a clean boot is deferred
+	installItemFunc = fakeInstallItem
+	installItemURL = ""
+	item.RequiresCleanBoot = true
+	have, err := Install(item, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+	if err != nil {
+		t.Errorf("Expected no error: %v", err)
+	}
+	if want := "Deferred until after a reboot"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if installItemURL != "" {
+		t.Errorf("Expected the installer not to run")
+	}
+	if have, want := rpt.Items[1].Outcome, report.OutcomeDeferred; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
+
+// TestInstallRestartAction verifies restart_action requires a reboot after a successful install
+func TestInstallRestartAction(t *testing.T) {
+	origCfg := installerCfg
+	defer func() { installerCfg = origCfg }()
+	installerCfg = config.Configuration{AppDataPath: t.TempDir()}
+
+	statusCheck = fakeCheckStatus
+	installItemFunc = fakeInstallItem
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+
+	rpt := &report.RunReport{}
+	item := exeItem
+	item.Name = "Drivers"
+	item.DisplayName = statusActionNoError
+	item.RestartAction = RestartRequire
+	Install(item, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+
+	if !rpt.Items[0].RebootRequired {
+		t.Errorf("Expected the item to require a reboot")
+	}
+	items, err := reboot.Pending(installerCfg.AppDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0] != "Drivers" {
+		t.Errorf("Expected Drivers to be waiting for a reboot, have %v", items)
+	}
+}
@@ -0,0 +1,18 @@
+package installer
+
+import (
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/command"
+)
+
+// installerTimeout returns how long an item's installer or uninstaller may run
+func installerTimeout(item catalog.Item) time.Duration {
+	return command.Timeout(item.InstallerTimeout, installerCfg.InstallerTimeout)
+}
+
+// scriptTimeout returns how long an item's preinstall and postinstall scripts may run
+func scriptTimeout(item catalog.Item) time.Duration {
+	return command.Timeout(item.ScriptTimeout, installerCfg.ScriptTimeout)
+}
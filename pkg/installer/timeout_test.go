@@ -0,0 +1,85 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/command"
+	"github.com/1dustindavis/gorilla/pkg/config"
+	"github.com/1dustindavis/gorilla/pkg/report"
+)
+
+// TestTimeouts verifies an item's own timeouts replace the global ones
+func TestTimeouts(t *testing.T) {
+	origCfg := installerCfg
+	defer func() { installerCfg = origCfg }()
+	installerCfg = config.Configuration{InstallerTimeout: "1h", ScriptTimeout: "10m"}
+
+	item := catalog.Item{}
+	if have, want := installerTimeout(item), time.Hour; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := scriptTimeout(item), 10*time.Minute; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+
+	item.InstallerTimeout, item.ScriptTimeout = "30m", "1m"
+	if have, want := installerTimeout(item), 30*time.Minute; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := scriptTimeout(item), time.Minute; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
+
+// TestInstallTimedOut verifies an installer that times out is recorded as timed out
+func TestInstallTimedOut(t *testing.T) {
+	statusCheck = fakeCheckStatus
+	defer func() {
+		statusCheck = origCheckStatus
+		installItemFunc = origInstallItemFunc
+	}()
+	installItemFunc = func(item catalog.Item, itemURL, cachePath string) (string, error) {
+		return "", fmt.Errorf("%w after 1h0m0s", command.ErrTimeout)
+	}
+
+	rpt := &report.RunReport{}
+	item := msiItem
+	item.DisplayName = statusActionNoError
+	Install(item, "install", "https://example.com/", "testdata/", checkOnlyMode, rpt)
+
+	if have, want := rpt.Items[0].Outcome, report.OutcomeTimedOut; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
+
+// TestRunCMDTimeoutWithOpenOutput verifies a timed out command returns even if a process it
+// started escaped the kill and still holds stdout open
+func TestRunCMDTimeoutWithOpenOutput(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid is not available")
+	}
+	execCommand = func(name string, arg ...string) *exec.Cmd {
+		cmd := exec.Command(name, arg...)
+		cmd.WaitDelay = 100 * time.Millisecond
+		return cmd
+	}
+	defer func() { execCommand = origExec }()
+
+	// setsid moves the background sleep out of the process group, so it is not killed
+	start := time.Now()
+	output, err := runCMD("sh", []string{"-c", "setsid sleep 3 & echo started; sleep 30"}, 100*time.Millisecond)
+	if !errors.Is(err, command.ErrTimeout) {
+		t.Errorf("Expected a timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected runCMD to return after the timeout, it took %s", elapsed)
+	}
+	if have, want := output, "started"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/signing"
 	"go.yaml.in/yaml/v4"
 )
 
@@ -22,7 +23,7 @@ type Item struct {
 }
 
 // This abstraction allows us to override when testing
-var downloadGet = download.Get
+var downloadGet = download.Cached
 
 // Get returns:
 // 1) All manifest objects
@@ -38,6 +39,12 @@ func Get(cfg config.Configuration) (manifests []Item, newCatalogs []string, err
 	var manifestsProcessed = 0
 	var manifestsRemaining = 1
 
+	// Load the keys used to verify each manifest before we parse it
+	verifier, err := signing.NewVerifier(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Add the top level manifest to the list
 	manifestsList = append(manifestsList, cfg.Manifest)
 
@@ -54,6 +61,9 @@ func Get(cfg config.Configuration) (manifests []Item, newCatalogs []string, err
 		if err != nil {
 			return nil, nil, err
 		}
+		if err := verifier.VerifyURL(manifestURL, yamlFile, downloadGet); err != nil {
+			return nil, nil, err
+		}
 
 		newManifest, err := parseManifest(manifestURL, yamlFile)
 		if err != nil {
@@ -0,0 +1,243 @@
+package process
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+)
+
+// CycleError is returned when an item's dependencies eventually depend on the item itself
+type CycleError struct {
+	// Path lists each item in the cycle, starting and ending with the same item
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", strings.Join(e.Path, " -> "))
+}
+
+// MissingDependencyError is returned when a dependency is not a valid item in any catalog
+type MissingDependencyError struct {
+	Item       string
+	Dependency string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("%s depends on %s, which was not found in any catalog", e.Item, e.Dependency)
+}
+
+// UnresolvedDependencyError is returned when a dependency exists but could not be resolved itself
+type UnresolvedDependencyError struct {
+	Item       string
+	Dependency string
+}
+
+func (e *UnresolvedDependencyError) Error() string {
+	return fmt.Sprintf("%s depends on %s, which could not be resolved", e.Item, e.Dependency)
+}
+
+// Track the state of each item while walking the dependency graph
+const (
+	stateUnvisited = iota
+	stateVisiting
+	stateResolved
+	stateFailed
+)
+
+// resolver walks the dependency graph of a set of items
+type resolver struct {
+	catalogsMap map[int]map[string]catalog.Item
+	state       map[string]int
+	missing     map[string]bool
+	cycles      map[string]bool // items on a cycle that has already been reported
+	stack       []string
+	order       []string
+	errs        []error
+	// chains records the path of dependents that first led to each item
+	chains map[string][]string
+}
+
+// newResolver returns a resolver that has walked the dependency graph of `items`
+func newResolver(items []string, catalogsMap map[int]map[string]catalog.Item) *resolver {
+	r := &resolver{
+		catalogsMap: catalogsMap,
+		state:       make(map[string]int),
+		missing:     make(map[string]bool),
+		cycles:      make(map[string]bool),
+		chains:      make(map[string][]string),
+	}
+	for _, item := range items {
+		r.visit(item)
+	}
+	return r
+}
+
+// ResolveDependencies expands the transitive dependencies of each item and returns
+// every item exactly once, ordered so that dependencies come before their dependents.
+// Items that are part of a cycle, or that depend on a missing item, are left out
+// of the order and described in the returned errors.
+func ResolveDependencies(items []string, catalogsMap map[int]map[string]catalog.Item) ([]string, []error) {
+	r := newResolver(items, catalogsMap)
+	return r.order, r.errs
+}
+
+// visit resolves an item and its dependencies, returning false if the item can not be installed
+func (r *resolver) visit(itemName string) bool {
+	switch r.state[itemName] {
+	case stateResolved:
+		return true
+	case stateFailed:
+		return false
+	case stateVisiting:
+		// Find where this item entered the stack to describe the full cycle
+		start := 0
+		for i, name := range r.stack {
+			if name == itemName {
+				start = i
+				break
+			}
+		}
+		path := append([]string{}, r.stack[start:]...)
+		path = append(path, itemName)
+		r.errs = append(r.errs, &CycleError{Path: path})
+		for _, name := range path {
+			r.cycles[name] = true
+		}
+		return false
+	}
+
+	if _, seen := r.chains[itemName]; !seen {
+		r.chains[itemName] = append(slices.Clone(r.stack), itemName)
+	}
+
+	validItem, ok := firstItem(itemName, r.catalogsMap)
+	if !ok {
+		r.state[itemName] = stateFailed
+		r.missing[itemName] = true
+		return false
+	}
+
+	r.state[itemName] = stateVisiting
+	r.stack = append(r.stack, itemName)
+
+	// Visit every dependency, even after a failure, so all problems are reported at once
+	resolved := true
+	for _, dependency := range validItem.Dependencies {
+		if r.visit(dependency) {
+			continue
+		}
+		resolved = false
+		// Cycles have already been described when they were detected, including every item on them
+		if r.missing[dependency] {
+			r.errs = append(r.errs, &MissingDependencyError{Item: itemName, Dependency: dependency})
+		} else if r.cycles[itemName] && r.cycles[dependency] {
+			continue
+		} else if r.state[dependency] == stateFailed {
+			r.errs = append(r.errs, &UnresolvedDependencyError{Item: itemName, Dependency: dependency})
+		}
+	}
+
+	r.stack = r.stack[:len(r.stack)-1]
+	if !resolved {
+		r.state[itemName] = stateFailed
+		return false
+	}
+
+	r.state[itemName] = stateResolved
+	r.order = append(r.order, itemName)
+	return true
+}
+
+// RequiredByError is returned when an item can not be uninstalled because
+// one or more managed installs still depend on it
+type RequiredByError struct {
+	Item       string
+	RequiredBy []string
+}
+
+func (e *RequiredByError) Error() string {
+	return fmt.Sprintf("%s is still required by managed installs: %s", e.Item, strings.Join(e.RequiredBy, ", "))
+}
+
+// dependencyClosure returns every item that `itemName` depends on, directly or transitively.
+// Missing items and cycles are ignored here; they are reported when resolving installs.
+func dependencyClosure(itemName string, catalogsMap map[int]map[string]catalog.Item) map[string]bool {
+	closure := make(map[string]bool)
+	pending := []string{itemName}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		item, _, ok := findItem(current, catalogsMap)
+		if !ok {
+			continue
+		}
+		for _, dependency := range item.Dependencies {
+			if dependency == itemName || closure[dependency] {
+				continue
+			}
+			closure[dependency] = true
+			pending = append(pending, dependency)
+		}
+	}
+	return closure
+}
+
+// PlanUninstalls orders uninstalls so that dependents are removed before the items they depend on.
+// Any item that is still required by one of the managed `installs` is left out of the order
+// and described by a `RequiredByError`.
+func PlanUninstalls(uninstalls, installs []string, catalogsMap map[int]map[string]catalog.Item) ([]string, []error) {
+	var errs []error
+
+	// Determine which managed installs need each item
+	requiredBy := make(map[string][]string)
+	for _, install := range installs {
+		for dependency := range dependencyClosure(install, catalogsMap) {
+			if !slices.Contains(requiredBy[dependency], install) {
+				requiredBy[dependency] = append(requiredBy[dependency], install)
+			}
+		}
+	}
+
+	// Build the list of items we are allowed to uninstall, preserving manifest order
+	var candidates []string
+	for _, item := range uninstalls {
+		if slices.Contains(candidates, item) {
+			continue
+		}
+		if needed := requiredBy[item]; len(needed) > 0 {
+			slices.Sort(needed)
+			errs = append(errs, &RequiredByError{Item: item, RequiredBy: needed})
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+
+	closures := make(map[string]map[string]bool)
+	for _, item := range candidates {
+		closures[item] = dependencyClosure(item, catalogsMap)
+	}
+
+	// Visit each candidate, emitting everything that depends on it first
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(item string)
+	visit = func(item string) {
+		if visited[item] {
+			return
+		}
+		visited[item] = true
+		for _, dependent := range candidates {
+			if closures[dependent][item] {
+				visit(dependent)
+			}
+		}
+		order = append(order, item)
+	}
+	for _, item := range candidates {
+		visit(item)
+	}
+
+	return order, errs
+}
@@ -0,0 +1,153 @@
+package process
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/report"
+)
+
+// dependencyItem returns a valid catalog item with the provided dependencies
+func dependencyItem(name string, dependencies ...string) catalog.Item {
+	return catalog.Item{
+		DisplayName:  name,
+		Dependencies: dependencies,
+		Installer: catalog.InstallerItem{
+			Type:     "msi",
+			Location: name + ".msi",
+		},
+	}
+}
+
+// TestResolveDependenciesTransitive verifies that multi-level chains are expanded in order
+func TestResolveDependenciesTransitive(t *testing.T) {
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"Runtime": dependencyItem("Runtime"),
+		"SDK":     dependencyItem("SDK", "Runtime"),
+		"App":     dependencyItem("App", "SDK"),
+		"Tool":    dependencyItem("Tool", "Runtime"),
+	}}
+
+	order, errs := ResolveDependencies([]string{"App", "Tool", "SDK"}, catalogs)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	expected := []string{"Runtime", "SDK", "App", "Tool"}
+	if !reflect.DeepEqual(expected, order) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, order)
+	}
+}
+
+// TestResolveDependenciesCycle verifies that cycles are reported and their items are skipped
+func TestResolveDependenciesCycle(t *testing.T) {
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"A":           dependencyItem("A", "B"),
+		"B":           dependencyItem("B", "C"),
+		"C":           dependencyItem("C", "A"),
+		"Independent": dependencyItem("Independent"),
+	}}
+
+	order, errs := ResolveDependencies([]string{"A", "Independent"}, catalogs)
+
+	expected := []string{"Independent"}
+	if !reflect.DeepEqual(expected, order) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, order)
+	}
+
+	// The cycle is only reported once, not again for each item on it
+	var cycleErr *CycleError
+	if len(errs) != 1 || !errors.As(errs[0], &cycleErr) {
+		t.Fatalf("expected only a cycle error, got: %v", errs)
+	}
+	expectedPath := []string{"A", "B", "C", "A"}
+	if !reflect.DeepEqual(expectedPath, cycleErr.Path) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expectedPath, cycleErr.Path)
+	}
+}
+
+// TestResolveDependenciesMissing verifies that missing dependencies are reported for each dependent
+func TestResolveDependenciesMissing(t *testing.T) {
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"SDK":   dependencyItem("SDK", "Runtime"),
+		"App":   dependencyItem("App", "SDK"),
+		"Other": dependencyItem("Other", "Runtime"),
+	}}
+
+	order, errs := ResolveDependencies([]string{"App", "Other"}, catalogs)
+	if len(order) != 0 {
+		t.Errorf("expected no items to be resolved, got: %#v", order)
+	}
+
+	expected := []error{
+		&MissingDependencyError{Item: "SDK", Dependency: "Runtime"},
+		&UnresolvedDependencyError{Item: "App", Dependency: "SDK"},
+		&MissingDependencyError{Item: "Other", Dependency: "Runtime"},
+	}
+	if !reflect.DeepEqual(expected, errs) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, errs)
+	}
+}
+
+// TestPlanUninstallsOrdersDependentsFirst verifies dependents are removed before their dependencies
+func TestPlanUninstallsOrdersDependentsFirst(t *testing.T) {
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"Runtime": dependencyItem("Runtime"),
+		"SDK":     dependencyItem("SDK", "Runtime"),
+		"App":     dependencyItem("App", "SDK"),
+		"Other":   dependencyItem("Other"),
+	}}
+
+	order, errs := PlanUninstalls([]string{"Runtime", "Other", "App", "SDK"}, nil, catalogs)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	expected := []string{"App", "SDK", "Runtime", "Other"}
+	if !reflect.DeepEqual(expected, order) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, order)
+	}
+}
+
+// TestPlanUninstallsRefusesRequiredItems verifies items needed by managed installs are not removed
+func TestPlanUninstallsRefusesRequiredItems(t *testing.T) {
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"Runtime": dependencyItem("Runtime"),
+		"SDK":     dependencyItem("SDK", "Runtime"),
+		"App":     dependencyItem("App", "SDK"),
+		"Tool":    dependencyItem("Tool", "Runtime"),
+		"Old":     dependencyItem("Old"),
+	}}
+
+	order, errs := PlanUninstalls([]string{"Runtime", "Old"}, []string{"Tool", "App"}, catalogs)
+
+	expected := []string{"Old"}
+	if !reflect.DeepEqual(expected, order) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, order)
+	}
+
+	expectedErrs := []error{&RequiredByError{Item: "Runtime", RequiredBy: []string{"App", "Tool"}}}
+	if !reflect.DeepEqual(expectedErrs, errs) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expectedErrs, errs)
+	}
+}
+
+// TestInstallsReportsDependencyErrors verifies unresolved dependencies are recorded in the report
+func TestInstallsReportsDependencyErrors(t *testing.T) {
+	installerInstall = fakeInstall
+	defer func() { installerInstall = origInstall }()
+
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"App": dependencyItem("App", "Runtime"),
+	}}
+
+	rpt := &report.RunReport{}
+	Installs([]string{"App"}, catalogs, "URLPackages", "CachePath", checkOnlyMode, rpt)
+
+	expected := []string{"App depends on Runtime, which was not found in any catalog"}
+	if !reflect.DeepEqual(expected, rpt.Errors) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, rpt.Errors)
+	}
+}
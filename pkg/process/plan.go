@@ -0,0 +1,192 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/status"
+)
+
+// Plan actions
+const (
+	ActionInstall   = "install"
+	ActionUpdate    = "update"
+	ActionUninstall = "uninstall"
+	ActionNone      = "none"
+)
+
+// PlanItem describes what a run would do with a single item
+type PlanItem struct {
+	Item            string   `json:"item"`
+	DisplayName     string   `json:"display_name"`
+	Action          string   `json:"action"`
+	Reason          string   `json:"reason"`
+	CheckMethod     string   `json:"check_method"`
+	DetectedVersion string   `json:"detected_version"`
+	DesiredVersion  string   `json:"desired_version"`
+	Catalog         string   `json:"catalog"`
+	DependencyChain []string `json:"dependency_chain,omitempty"`
+}
+
+// Plan is the ordered list of actions a run would take
+type Plan struct {
+	Items []PlanItem `json:"items"`
+}
+
+// This abstraction allows us to override when testing
+var statusCheck = status.Check
+
+// sourceCatalog returns the name of the catalog that provides the first valid occurrence of an item
+func sourceCatalog(itemName string, catalogsMap map[int]map[string]catalog.Item, catalogNames []string) string {
+	for i, name := range catalogNames {
+		single := map[int]map[string]catalog.Item{i + 1: catalogsMap[i+1]}
+		if _, _, ok := findItem(itemName, single); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// planItem checks the current status of an item and describes the resulting action
+func planItem(itemName, installType string, catalogsMap map[int]map[string]catalog.Item, catalogNames []string, cachePath string) PlanItem {
+	entry := PlanItem{Item: itemName, Action: ActionNone}
+
+	item, invalidReasons, ok := findItem(itemName, catalogsMap)
+	if !ok {
+		entry.Reason = "not found in any catalog"
+		if len(invalidReasons) > 0 {
+			entry.Reason = strings.Join(invalidReasons, "; ")
+		}
+		return entry
+	}
+	entry.DisplayName = item.DisplayName
+	entry.DesiredVersion = item.Version
+	entry.Catalog = sourceCatalog(itemName, catalogsMap, catalogNames)
+
+	result, err := statusCheck(item, installType, cachePath)
+	entry.CheckMethod = result.Method
+	entry.DetectedVersion = result.DetectedVersion
+	entry.Reason = result.Reason
+	if err != nil {
+		entry.Reason = fmt.Sprintf("unable to check status: %v", err)
+		return entry
+	}
+	if result.ActionNeeded {
+		entry.Action = installType
+	}
+	return entry
+}
+
+// BuildPlan checks the status of every managed item and describes the action a run would take,
+// without downloading or installing anything. `catalogNames` are the names of the catalogs
+// in the same order they were loaded into `catalogsMap`.
+func BuildPlan(installs, uninstalls, updates []string, catalogsMap map[int]map[string]catalog.Item, catalogNames []string, cachePath string) Plan {
+	var plan Plan
+	planned := make(map[string]bool)
+
+	// Installs, including dependencies, in the order they would be installed
+	r := newResolver(installs, catalogsMap)
+	for _, itemName := range r.order {
+		entry := planItem(itemName, ActionInstall, catalogsMap, catalogNames, cachePath)
+		if chain := r.chains[itemName]; len(chain) > 1 {
+			entry.DependencyChain = chain
+		}
+		plan.Items = append(plan.Items, entry)
+		planned[itemName] = true
+	}
+
+	// Describe why any install could not be resolved
+	for _, err := range r.errs {
+		var cycleErr *CycleError
+		var missingErr *MissingDependencyError
+		var unresolvedErr *UnresolvedDependencyError
+		var affected []string
+		switch {
+		case errors.As(err, &cycleErr):
+			affected = cycleErr.Path[:len(cycleErr.Path)-1]
+		case errors.As(err, &missingErr):
+			affected = []string{missingErr.Item}
+		case errors.As(err, &unresolvedErr):
+			affected = []string{unresolvedErr.Item}
+		}
+		for _, itemName := range affected {
+			if planned[itemName] {
+				continue
+			}
+			plan.Items = append(plan.Items, PlanItem{
+				Item:            itemName,
+				Action:          ActionNone,
+				Reason:          err.Error(),
+				Catalog:         sourceCatalog(itemName, catalogsMap, catalogNames),
+				DependencyChain: r.chains[itemName],
+			})
+			planned[itemName] = true
+		}
+	}
+
+	// Uninstalls, in the order they would be removed
+	ordered, errs := PlanUninstalls(uninstalls, installs, catalogsMap)
+	for _, itemName := range ordered {
+		plan.Items = append(plan.Items, planItem(itemName, ActionUninstall, catalogsMap, catalogNames, cachePath))
+	}
+	for _, err := range errs {
+		var requiredErr *RequiredByError
+		if errors.As(err, &requiredErr) {
+			plan.Items = append(plan.Items, PlanItem{
+				Item:    requiredErr.Item,
+				Action:  ActionNone,
+				Reason:  err.Error(),
+				Catalog: sourceCatalog(requiredErr.Item, catalogsMap, catalogNames),
+			})
+		}
+	}
+
+	// Updates, skipping anything that is already handled as an install
+	for _, itemName := range updates {
+		if planned[itemName] {
+			continue
+		}
+		plan.Items = append(plan.Items, planItem(itemName, ActionUpdate, catalogsMap, catalogNames, cachePath))
+		planned[itemName] = true
+	}
+
+	return plan
+}
+
+// WriteJSON writes the plan as an indented JSON document
+func (p Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// WriteTable writes the plan as a human readable table
+func (p Plan) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tACTION\tDETECTED\tDESIRED\tCATALOG\tREASON\tDEPENDENCY CHAIN")
+	for _, entry := range p.Items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Item,
+			entry.Action,
+			valueOrDash(entry.DetectedVersion),
+			valueOrDash(entry.DesiredVersion),
+			valueOrDash(entry.Catalog),
+			entry.Reason,
+			valueOrDash(strings.Join(entry.DependencyChain, " > ")),
+		)
+	}
+	return tw.Flush()
+}
+
+// valueOrDash keeps empty table cells readable
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,83 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/status"
+)
+
+// fakeStatusCheck reports an action is needed for every item except "Current"
+func fakeStatusCheck(item catalog.Item, installType, cachePath string) (status.Result, error) {
+	if item.DisplayName == "Current" {
+		return status.Result{Method: status.MethodRegistry, DetectedVersion: item.Version, Reason: "installed version is current"}, nil
+	}
+	return status.Result{ActionNeeded: true, Method: status.MethodRegistry, Reason: "not installed"}, nil
+}
+
+// TestBuildPlan verifies that a plan describes each action, version, catalog, and dependency chain
+func TestBuildPlan(t *testing.T) {
+	origStatusCheck := statusCheck
+	statusCheck = fakeStatusCheck
+	defer func() { statusCheck = origStatusCheck }()
+
+	runtime := dependencyItem("Runtime")
+	runtime.Version = "2.0"
+	current := dependencyItem("Current")
+	current.Version = "1.0"
+	catalogs := map[int]map[string]catalog.Item{
+		1: {
+			"App":     dependencyItem("App", "Runtime"),
+			"Current": current,
+			"Broken":  dependencyItem("Broken", "Nowhere"),
+		},
+		2: {
+			"Runtime": runtime,
+			"Old":     dependencyItem("Old"),
+		},
+	}
+
+	plan := BuildPlan([]string{"App", "Current", "Broken"}, []string{"Runtime", "Old"}, []string{"Current"}, catalogs, []string{"production", "testing"}, "cache")
+
+	expected := []PlanItem{
+		{Item: "Runtime", DisplayName: "Runtime", Action: ActionInstall, Reason: "not installed", CheckMethod: status.MethodRegistry, DesiredVersion: "2.0", Catalog: "testing", DependencyChain: []string{"App", "Runtime"}},
+		{Item: "App", DisplayName: "App", Action: ActionInstall, Reason: "not installed", CheckMethod: status.MethodRegistry, Catalog: "production"},
+		{Item: "Current", DisplayName: "Current", Action: ActionNone, Reason: "installed version is current", CheckMethod: status.MethodRegistry, DetectedVersion: "1.0", DesiredVersion: "1.0", Catalog: "production"},
+		{Item: "Broken", Action: ActionNone, Reason: "Broken depends on Nowhere, which was not found in any catalog", Catalog: "production", DependencyChain: []string{"Broken"}},
+		{Item: "Old", DisplayName: "Old", Action: ActionUninstall, Reason: "not installed", CheckMethod: status.MethodRegistry, Catalog: "testing"},
+		{Item: "Runtime", Action: ActionNone, Reason: "Runtime is still required by managed installs: App", Catalog: "testing"},
+	}
+	if !reflect.DeepEqual(expected, plan.Items) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, plan.Items)
+	}
+
+	// The JSON document should round trip
+	var buf bytes.Buffer
+	if err := plan.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Plan
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("unable to parse plan json: %v", err)
+	}
+	if !reflect.DeepEqual(plan, decoded) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", plan, decoded)
+	}
+
+	// The table should have a header and one row per item
+	buf.Reset()
+	if err := plan.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if have, want := len(lines), len(expected)+1; have != want {
+		t.Errorf("have %d table lines, want %d", have, want)
+	}
+	if !strings.Contains(lines[1], "App > Runtime") {
+		t.Errorf("expected dependency chain in table row: %s", lines[1])
+	}
+}
@@ -0,0 +1,103 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/download"
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+)
+
+// This abstraction allows us to override when testing
+var downloadPackage = download.Package
+
+// prefetchJob is a single package to download
+type prefetchJob struct {
+	item     string
+	location string
+	url      string
+	hash     string
+}
+
+// prefetchJobs returns the packages needed by the actions in `plan`, without duplicates
+func prefetchJobs(plan Plan, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string) []prefetchJob {
+	var jobs []prefetchJob
+	seen := make(map[string]bool)
+	for _, entry := range plan.Items {
+		if entry.Action == ActionNone {
+			continue
+		}
+		item, _, ok := findItem(entry.Item, catalogsMap)
+		if !ok {
+			continue
+		}
+
+		// Uninstalls use the uninstaller, everything else uses the installer
+		pkg := item.Installer
+		if entry.Action == ActionUninstall {
+			pkg = item.Uninstaller
+		}
+		if pkg.Location == "" {
+			continue
+		}
+
+		// Packages with the same content are only downloaded once
+		absFile := download.PackagePath(cachePath, pkg.Location, pkg.Hash)
+		if seen[absFile] {
+			continue
+		}
+		seen[absFile] = true
+
+		jobs = append(jobs, prefetchJob{
+			item:     entry.Item,
+			location: pkg.Location,
+			url:      urlPackages + pkg.Location,
+			hash:     pkg.Hash,
+		})
+	}
+	return jobs
+}
+
+// Prefetch downloads and verifies every package needed by `plan` before anything is installed.
+// Up to `parallelism` packages are downloaded at the same time. A failed package does not stop the
+// others, and an error describing every failed package is returned.
+func Prefetch(plan Plan, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, parallelism int) error {
+	jobs := prefetchJobs(plan, catalogsMap, urlPackages, cachePath)
+	if len(jobs) == 0 {
+		return nil
+	}
+	parallelism = max(parallelism, 1)
+	gorillalog.Info("Prefetching", len(jobs), "packages,", parallelism, "at a time")
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	slots := make(chan struct{}, parallelism)
+	for _, job := range jobs {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(job prefetchJob) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			_, err := downloadPackage(cachePath, job.location, job.url, job.hash)
+			if errors.Is(err, download.ErrNoHash) {
+				// Leave it to the installer to record the item as rejected
+				gorillalog.Warn("Not prefetching", job.item+":", err)
+				return
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("unable to download valid package for %s: %s: %w", job.item, job.url, err))
+				mu.Unlock()
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package process
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestPrefetch verifies the packages needed by a plan are downloaded once each
+func TestPrefetch(t *testing.T) {
+	origDownload := downloadPackage
+	defer func() { downloadPackage = origDownload }()
+
+	var mu sync.Mutex
+	var downloaded []string
+	downloadPackage = func(cachePath, location, url, hash string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		downloaded = append(downloaded, url)
+		return "", nil
+	}
+
+	plan := Plan{Items: []PlanItem{
+		{Item: "TestInstall1", Action: ActionInstall},
+		{Item: "CanonDrivers", Action: ActionUpdate},
+		{Item: "AdobeFlash", Action: ActionUninstall},
+		{Item: "GoogleChrome", Action: ActionNone},
+	}}
+
+	err := Prefetch(plan, testCatalogs, "https://example.com/packages/", filepath.Clean("testdata/cache"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CanonDrivers uses the same package as TestInstall1, and GoogleChrome is already installed
+	expected := []string{"https://example.com/packages/AdobeUninst.msi", "https://example.com/packages/TestInstall1.msi"}
+	slices.Sort(downloaded)
+	if !reflect.DeepEqual(expected, downloaded) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, downloaded)
+	}
+}
+
+// TestPrefetchContinues verifies a failed download does not stop the others
+func TestPrefetchContinues(t *testing.T) {
+	origDownload := downloadPackage
+	defer func() { downloadPackage = origDownload }()
+
+	var downloaded []string
+	downloadPackage = func(cachePath, location, url, hash string) (string, error) {
+		downloaded = append(downloaded, url)
+		if strings.HasSuffix(url, "TestInstall1.msi") {
+			return "", errors.New("404 not found")
+		}
+		return "", nil
+	}
+
+	plan := Plan{Items: []PlanItem{
+		{Item: "TestInstall1", Action: ActionInstall},
+		{Item: "GoogleChrome", Action: ActionInstall},
+	}}
+
+	// With one download at a time, the failure comes first
+	err := Prefetch(plan, testCatalogs, "https://example.com/packages/", filepath.Clean("testdata/cache"), 1)
+	if err == nil || !strings.Contains(err.Error(), "TestInstall1") {
+		t.Fatalf("Expected an error naming TestInstall1, got: %v", err)
+	}
+	if have, want := len(downloaded), 2; have != want {
+		t.Errorf("have %d downloads, want %d", have, want)
+	}
+}
@@ -1,6 +1,7 @@
 package process
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,14 +11,16 @@ import (
 	"time"
 
 	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
 	"github.com/1dustindavis/gorilla/pkg/installer"
 	"github.com/1dustindavis/gorilla/pkg/manifest"
+	"github.com/1dustindavis/gorilla/pkg/report"
 )
 
-// firstItem returns the first valid occurrence of an item in a map of catalogs.
-// It logs warnings for invalid/missing items and returns false when no valid item is found.
-func firstItem(itemName string, catalogsMap map[int]map[string]catalog.Item) (catalog.Item, bool) {
+// findItem returns the first valid occurrence of an item in a map of catalogs.
+// When no valid item is found, it returns the reasons any matching items were invalid.
+func findItem(itemName string, catalogsMap map[int]map[string]catalog.Item) (catalog.Item, []string, bool) {
 	// Get the keys in the map and sort them so we can loop over them in order
 	keys := make([]int, 0)
 	for k := range catalogsMap {
@@ -32,34 +35,55 @@ func firstItem(itemName string, catalogsMap map[int]map[string]catalog.Item) (ca
 		// If
 		if item, exists := catalogsMap[k][itemName]; exists {
 			// If it does exist, we should confirm it is a valid item
-			validInstallItem := (item.Installer.Type != "" && item.Installer.Location != "")
-			validUninstallItem := (item.Uninstaller.Type != "" && item.Uninstaller.Location != "")
-
-			if validInstallItem || validUninstallItem {
-				return item, true
+			if validPackage(item.Installer) || validPackage(item.Uninstaller) {
+				return item, nil, true
 			}
 
-			missing := []string{}
-			if item.Installer.Type == "" {
-				missing = append(missing, "installer.type")
-			}
-			if item.Installer.Location == "" {
-				missing = append(missing, "installer.location")
-			}
-			if item.Uninstaller.Type == "" {
-				missing = append(missing, "uninstaller.type")
-			}
-			if item.Uninstaller.Location == "" {
-				missing = append(missing, "uninstaller.location")
-			}
+			missing := append(missingFields("installer", item.Installer), missingFields("uninstaller", item.Uninstaller)...)
 			invalidReasons = append(invalidReasons, fmt.Sprintf("catalog index %d missing required fields: %s", k, strings.Join(missing, ", ")))
 		}
 	}
 
+	return catalog.Item{}, invalidReasons, false
+}
+
+// validPackage returns true if an installer or uninstaller can be run. Script only ("nopkg")
+// items need a script instead of a location.
+func validPackage(pkg catalog.InstallerItem) bool {
+	if pkg.Type == "nopkg" {
+		return pkg.Script != ""
+	}
+	return pkg.Type != "" && pkg.Location != ""
+}
+
+// missingFields returns the fields an installer or uninstaller needs, but does not have
+func missingFields(name string, pkg catalog.InstallerItem) []string {
+	missing := []string{}
+	if pkg.Type == "" {
+		missing = append(missing, name+".type")
+	}
+	if pkg.Type == "nopkg" {
+		if pkg.Script == "" {
+			missing = append(missing, name+".script")
+		}
+	} else if pkg.Location == "" {
+		missing = append(missing, name+".location")
+	}
+	return missing
+}
+
+// firstItem returns the first valid occurrence of an item in a map of catalogs.
+// It logs warnings for invalid/missing items and returns false when no valid item is found.
+func firstItem(itemName string, catalogsMap map[int]map[string]catalog.Item) (catalog.Item, bool) {
+	item, invalidReasons, ok := findItem(itemName, catalogsMap)
+	if ok {
+		return item, true
+	}
+
 	// No valid item found. Log why and continue processing other items.
 	if len(invalidReasons) > 0 {
 		gorillalog.Warn(fmt.Sprintf(
-			"skipping catalog item %q because it is missing required installer/uninstaller type/location fields (%s)",
+			"skipping catalog item %q because it is missing required installer/uninstaller type/location/script fields (%s)",
 			itemName,
 			strings.Join(invalidReasons, "; "),
 		))
@@ -114,35 +138,45 @@ func Manifests(manifests []manifest.Item, catalogsMap map[int]map[string]catalog
 // This abstraction allows us to override when testing
 var installerInstall = installer.Install
 
-// Installs prepares and then installs an array of items
-func Installs(installs []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, CheckOnly bool) {
-	// Iterate through the installs array, install dependencies, and then the item itself
-	for _, item := range installs {
+// Installs prepares and then installs an array of items, recording the results in `rpt`.
+// Every item keeps being processed when one fails, and the failures are returned together.
+func Installs(installs []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, CheckOnly bool, rpt *report.RunReport) error {
+	// Expand all dependencies so each item is installed once, after everything it depends on
+	ordered, errs := ResolveDependencies(installs, catalogsMap)
+	for _, err := range errs {
+		gorillalog.Warn("Unable to resolve dependencies:", err)
+		rpt.AddError(err)
+	}
+
+	// Iterate through the ordered installs and install each item
+	for _, item := range ordered {
 		// Get the first valid item from our catalogs
 		// Continue to the next item in the loop if we get an error
 		validItem, ok := firstItem(item, catalogsMap)
 		if !ok {
 			continue
 		}
-		// Check for dependencies and install if found
-		if len(validItem.Dependencies) > 0 {
-			for _, dependency := range validItem.Dependencies {
-				validDependency, ok := firstItem(dependency, catalogsMap)
-				if !ok {
-					continue
-				}
-				installerInstall(validDependency, "install", urlPackages, cachePath, CheckOnly)
-			}
-		}
 		// Install the item
-		installerInstall(validItem, "install", urlPackages, cachePath, CheckOnly)
+		if _, err := installerInstall(validItem, "install", urlPackages, cachePath, CheckOnly, rpt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item, err))
+		}
 	}
+	return errors.Join(errs...)
 }
 
-// Uninstalls prepares and then installs an array of items
-func Uninstalls(uninstalls []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, CheckOnly bool) {
-	// Iterate through the uninstalls array and uninstall the item
-	for _, item := range uninstalls {
+// Uninstalls prepares and then uninstalls an array of items, recording the results in `rpt`
+// Dependents are removed before their dependencies, and items still required by `installs` are skipped.
+// Every item keeps being processed when one fails, and the failures are returned together.
+func Uninstalls(uninstalls, installs []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, CheckOnly bool, rpt *report.RunReport) error {
+	// Order the uninstalls and refuse anything a managed install still needs
+	ordered, errs := PlanUninstalls(uninstalls, installs, catalogsMap)
+	for _, err := range errs {
+		gorillalog.Warn("Refusing to uninstall:", err)
+		rpt.AddError(err)
+	}
+
+	// Iterate through the ordered uninstalls and uninstall the item
+	for _, item := range ordered {
 		// Get the first valid item from our catalogs
 		// Continue to the next item in the loop if we get an error
 		validItem, ok := firstItem(item, catalogsMap)
@@ -150,12 +184,17 @@ func Uninstalls(uninstalls []string, catalogsMap map[int]map[string]catalog.Item
 			continue
 		}
 		// Uninstall the item
-		installerInstall(validItem, "uninstall", urlPackages, cachePath, CheckOnly)
+		if _, err := installerInstall(validItem, "uninstall", urlPackages, cachePath, CheckOnly, rpt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item, err))
+		}
 	}
+	return errors.Join(errs...)
 }
 
-// Updates prepares and then installs an array of items
-func Updates(updates []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, CheckOnly bool) {
+// Updates prepares and then installs an array of items, recording the results in `rpt`.
+// Every item keeps being processed when one fails, and the failures are returned together.
+func Updates(updates []string, catalogsMap map[int]map[string]catalog.Item, urlPackages, cachePath string, CheckOnly bool, rpt *report.RunReport) error {
+	var errs []error
 	// Iterate through the updates array and update the item **if it is already installed**
 	for _, item := range updates {
 		// Get the first valid item from our catalogs
@@ -165,8 +204,11 @@ func Updates(updates []string, catalogsMap map[int]map[string]catalog.Item, urlP
 			continue
 		}
 		// Update the item
-		installerInstall(validItem, "update", urlPackages, cachePath, CheckOnly)
+		if _, err := installerInstall(validItem, "update", urlPackages, cachePath, CheckOnly, rpt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item, err))
+		}
 	}
+	return errors.Join(errs...)
 }
 
 // dirEmpty returns true if the directory is empty
@@ -184,41 +226,84 @@ func dirEmpty(path string) bool {
 	return err == io.EOF
 }
 
-// fileOld returns true if the file is older than
-// the limit defined in the variable `days`
-func fileOld(info os.FileInfo) bool {
-	// Age of the file
-	fileAge := time.Since(info.ModTime())
+// This abstraction allows us to override when testing
+var osRemove = os.Remove
 
-	// Our limit
-	days := 5
+// cachedFile is a file in the cache that can be removed
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
 
-	// Convert from days
-	hours := days * 24
-	ageLimit := time.Duration(hours) * time.Hour
+// ManagedPackages returns the cache paths of the packages used by every managed item, including
+// dependencies. `CleanUp` never removes these, even if they are older or larger than the limits.
+func ManagedPackages(installs, uninstalls, updates []string, catalogsMap map[int]map[string]catalog.Item, cachePath string) []string {
+	var packages []string
+	addPackage := func(itemName string, uninstall bool) {
+		item, _, ok := findItem(itemName, catalogsMap)
+		if !ok {
+			return
+		}
+		pkg := item.Installer
+		if uninstall {
+			pkg = item.Uninstaller
+		}
+		if pkg.Location != "" {
+			packages = append(packages, download.PackagePath(cachePath, pkg.Location, pkg.Hash))
+		}
+	}
 
-	// If the file is older than our limit, return true
-	return fileAge > ageLimit
+	for _, itemName := range newResolver(installs, catalogsMap).order {
+		addPackage(itemName, false)
+	}
+	for _, itemName := range updates {
+		addPackage(itemName, false)
+	}
+	for _, itemName := range uninstalls {
+		addPackage(itemName, true)
+	}
+	return packages
 }
 
-// This abstraction allows us to override when testing
-var osRemove = os.Remove
-
-// CleanUp checks the age of items in the cache and removes if older than 10 days
-func CleanUp(cachePath string) {
+// CleanUp removes cached packages that have not been used within `maxAge`, then removes the least
+// recently used packages until the cache is no larger than `maxSize`. A limit of 0 is not enforced.
+// Anything in `keep` is never removed, and cached copies of manifests and catalogs are left alone.
+func CleanUp(cachePath string, keep []string, maxAge time.Duration, maxSize int64) {
+	metadataPath := filepath.Join(cachePath, download.MetadataDir)
+	keepSet := make(map[string]bool)
+	for _, path := range keep {
+		keepSet[filepath.Clean(path)] = true
+	}
 
 	// Clean up old files
+	var totalSize int64
+	var candidates []cachedFile
 	err := filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			gorillalog.Warn("Failed to access path:", path, err)
 			return err
 		}
-		// If not a directory and older that our limit, delete
-		if !info.IsDir() && fileOld(info) {
+		// Cached manifests and catalogs are kept for offline use
+		if info.IsDir() && path == metadataPath {
+			return filepath.SkipDir
+		}
+		if info.IsDir() {
+			return nil
+		}
+		// Packages for managed items are always kept
+		if keepSet[filepath.Clean(path)] {
+			totalSize += info.Size()
+			return nil
+		}
+		// If older than our limit, delete
+		if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
 			gorillalog.Info("Cleaning old cached file:", info.Name())
 			osRemove(path)
 			return nil
 		}
+		totalSize += info.Size()
+		candidates = append(candidates, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
 		return nil
 	})
 	if err != nil {
@@ -226,6 +311,24 @@ func CleanUp(cachePath string) {
 		return
 	}
 
+	// If the cache is still too large, remove the least recently used files first
+	if maxSize > 0 && totalSize > maxSize {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].modTime.Before(candidates[j].modTime)
+		})
+		for _, candidate := range candidates {
+			if totalSize <= maxSize {
+				break
+			}
+			gorillalog.Info("Cleaning cached file to reduce cache size:", filepath.Base(candidate.path))
+			osRemove(candidate.path)
+			totalSize -= candidate.size
+		}
+		if totalSize > maxSize {
+			gorillalog.Warn("Cache is larger than the limit, but everything left is in use:", totalSize, "bytes")
+		}
+	}
+
 	// Clean up empty directories
 	err = filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -233,6 +336,10 @@ func CleanUp(cachePath string) {
 			return err
 		}
 
+		if info.IsDir() && path == metadataPath {
+			return filepath.SkipDir
+		}
+
 		// If a dir and empty, delete
 		if info.IsDir() && dirEmpty(path) {
 			gorillalog.Info("Cleaning empty directory:", info.Name())
@@ -1,6 +1,7 @@
 package process
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/1dustindavis/gorilla/pkg/catalog"
 	"github.com/1dustindavis/gorilla/pkg/manifest"
+	"github.com/1dustindavis/gorilla/pkg/report"
 )
 
 var (
@@ -204,7 +206,7 @@ func TestInstalls(t *testing.T) {
 	defer func() { installerInstall = origInstall }()
 
 	// Run `Installs` with test data
-	Installs(testInstalls, testCatalogs, "URLPackages", "CachePath", checkOnlyMode)
+	Installs(testInstalls, testCatalogs, "URLPackages", "CachePath", checkOnlyMode, nil)
 
 	// Define what we expect to be in the list of installed items
 	// This ends up being the testInstalls slice *PLUS any dependencies*
@@ -227,7 +229,7 @@ func TestUninstalls(t *testing.T) {
 	defer func() { installerInstall = origInstall }()
 
 	// Run `Uninstalls` with test data
-	Uninstalls(testUninstalls, testCatalogs, "URLPackages", "CachePath", checkOnlyMode)
+	Uninstalls(testUninstalls, testInstalls, testCatalogs, "URLPackages", "CachePath", checkOnlyMode, nil)
 
 	// Define what we expect to be in the list of uninstalled items
 	expectedItems := testUninstalls
@@ -249,7 +251,7 @@ func TestUpdates(t *testing.T) {
 	defer func() { installerInstall = origInstall }()
 
 	// Run `Updates` with test data
-	Updates(testUpdates, testCatalogs, "URLPackages", "CachePath", checkOnlyMode)
+	Updates(testUpdates, testCatalogs, "URLPackages", "CachePath", checkOnlyMode, nil)
 
 	// Define what we expect to be in the list of updated items
 	expectedItems := testUpdates
@@ -281,6 +283,7 @@ func TestCleanUp(t *testing.T) {
 	oldFile := filepath.Clean("testdata/cache/old.msi")
 	newFile := filepath.Clean("testdata/cache/new.msi")
 	childFile := filepath.Clean("testdata/cache/full/file.msi")
+	metadataFile := filepath.Clean("testdata/metadata/old.yaml")
 
 	// Set the timestamps on each test file
 	err := os.Chtimes(oldFile, oldTime, oldTime)
@@ -295,6 +298,10 @@ func TestCleanUp(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	err = os.Chtimes(metadataFile, oldTime, oldTime)
+	if err != nil {
+		t.Error(err)
+	}
 
 	// Create an empty directory if it doesn't already exist
 	if _, err := os.Stat(emptyDir); os.IsNotExist(err) {
@@ -303,7 +310,7 @@ func TestCleanUp(t *testing.T) {
 	}
 
 	// Run `CleanUp`
-	CleanUp("testdata/")
+	CleanUp("testdata/", nil, 120*time.Hour, 0)
 
 	// Define the files and directories we expect to be deleted
 	expectedFiles := []string{oldFile, emptyDir}
@@ -317,25 +324,72 @@ func TestCleanUp(t *testing.T) {
 	}
 }
 
+// TestCleanUpLimits verifies that packages in use are kept and the least recently used are removed first
+func TestCleanUpLimits(t *testing.T) {
+	cachePath := t.TempDir()
+
+	// Create four 1KB packages, each used a day apart
+	var packages []string
+	for i, name := range []string{"managed.msi", "oldest.msi", "older.msi", "newest.msi"} {
+		path := filepath.Join(cachePath, "cas", "sha256", name, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, 1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+		usedTime := time.Now().Add(-time.Duration(4-i) * 24 * time.Hour)
+		if err := os.Chtimes(path, usedTime, usedTime); err != nil {
+			t.Fatal(err)
+		}
+		packages = append(packages, path)
+	}
+
+	// The managed package is the oldest, but it should be kept along with the newest package
+	CleanUp(cachePath, packages[:1], 0, 2048)
+
+	for i, path := range packages {
+		_, err := os.Stat(path)
+		if have, want := err == nil, i == 0 || i == 3; have != want {
+			t.Errorf("%s: have exists %v, want %v", filepath.Base(path), have, want)
+		}
+	}
+}
+
+// TestManagedPackages verifies the packages for managed items are found, including dependencies
+func TestManagedPackages(t *testing.T) {
+	cachePath := filepath.Clean("testdata/cache")
+	actual := ManagedPackages([]string{"Chocolatey"}, []string{"AdobeFlash"}, []string{"GoogleChrome"}, testCatalogs, cachePath)
+	expected := []string{
+		filepath.Join(cachePath, "TestUpdate1.nupkg"),
+		filepath.Join(cachePath, "Chocolatey.msi"),
+		filepath.Join(cachePath, "GoogleChrome.msi"),
+		filepath.Join(cachePath, "AdobeUninst.msi"),
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
+
 // Mocks the actual `installer.Install` function and saves what it receives to `actualInstalledItems`
-func fakeInstall(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool) string {
+func fakeInstall(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool, rpt *report.RunReport) (string, error) {
 	// Append any item we are passed to a slice for later comparison
 	actualInstalledItems = append(actualInstalledItems, item.DisplayName)
-	return ""
+	return "", nil
 }
 
 // Mocks the actual `installer.Install` function and saves what it receives to `actualUninstalledItems`
-func fakeUninstall(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool) string {
+func fakeUninstall(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool, rpt *report.RunReport) (string, error) {
 	// Append any item we are passed to a slice for later comparison
 	actualUninstalledItems = append(actualUninstalledItems, item.DisplayName)
-	return ""
+	return "", nil
 }
 
 // Mocks the actual `installer.Install` function and saves what it receives to `actualUpdatedItems`
-func fakeUpdate(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool) string {
+func fakeUpdate(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool, rpt *report.RunReport) (string, error) {
 	// Append any item we are passed to a slice for later comparison
 	actualUpdatedItems = append(actualUpdatedItems, item.DisplayName)
-	return ""
+	return "", nil
 }
 
 // Mock `os.Remove` so we dont delete files during testing
@@ -343,3 +397,56 @@ func fakeOsRemove(name string) error {
 	actualRemovedFiles = append(actualRemovedFiles, name)
 	return nil
 }
+
+// TestFindItemNopkg verifies a script only item needs a script instead of a location
+func TestFindItemNopkg(t *testing.T) {
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"Telemetry": {
+			DisplayName: "Telemetry",
+			Installer:   catalog.InstallerItem{Type: "nopkg", Script: "Set-ItemProperty"},
+		},
+		"NoScript": {
+			DisplayName: "NoScript",
+			Installer:   catalog.InstallerItem{Type: "nopkg"},
+		},
+	}}
+
+	if _, _, ok := findItem("Telemetry", catalogs); !ok {
+		t.Errorf("Expected a nopkg item with a script to be valid")
+	}
+
+	_, reasons, ok := findItem("NoScript", catalogs)
+	if ok {
+		t.Errorf("Expected a nopkg item without a script to be invalid")
+	}
+	expected := []string{"catalog index 1 missing required fields: installer.script, uninstaller.type, uninstaller.location"}
+	if !reflect.DeepEqual(expected, reasons) {
+		t.Errorf("\nExpected: %#v\nActual:   %#v", expected, reasons)
+	}
+}
+
+// TestInstallsReturnsItemErrors verifies every item is processed when one fails, and the failures are returned
+func TestInstallsReturnsItemErrors(t *testing.T) {
+	var attempted []string
+	installerInstall = func(item catalog.Item, installerType string, urlPackages string, cachePath string, checkOnly bool, rpt *report.RunReport) (string, error) {
+		attempted = append(attempted, item.Name)
+		if item.Name == "App" {
+			return "", errors.New("installer failed")
+		}
+		return "", nil
+	}
+	defer func() { installerInstall = origInstall }()
+
+	catalogs := map[int]map[string]catalog.Item{1: {
+		"App":  {Name: "App", Installer: catalog.InstallerItem{Type: "msi", Location: "app.msi"}},
+		"Tool": {Name: "Tool", Installer: catalog.InstallerItem{Type: "msi", Location: "tool.msi"}},
+	}}
+
+	err := Installs([]string{"App", "Tool"}, catalogs, "URLPackages", "CachePath", checkOnlyMode, nil)
+	if err == nil || err.Error() != "App: installer failed" {
+		t.Errorf("Expected the App failure to be returned, got: %v", err)
+	}
+	if expected := []string{"App", "Tool"}; !reflect.DeepEqual(expected, attempted) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, attempted)
+	}
+}
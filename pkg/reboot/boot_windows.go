@@ -0,0 +1,23 @@
+//go:build windows
+
+package reboot
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32           = windows.NewLazySystemDLL("kernel32.dll")
+	procGetTickCount64 = kernel32.NewProc("GetTickCount64")
+)
+
+// systemBootTime returns when Windows started, from the number of milliseconds it has been running
+func systemBootTime() (time.Time, error) {
+	if err := procGetTickCount64.Find(); err != nil {
+		return time.Time{}, err
+	}
+	uptime, _, _ := procGetTickCount64.Call()
+	return time.Now().Add(-time.Duration(uptime) * time.Millisecond).Truncate(time.Second), nil
+}
@@ -0,0 +1,103 @@
+package reboot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MarkerFile is written to the app data path while a reboot is pending
+const MarkerFile = "reboot-pending.json"
+
+// bootTolerance allows for the boot time being calculated slightly differently each time
+const bootTolerance = time.Minute
+
+// marker records why a reboot is needed and when the system last started
+type marker struct {
+	BootTime time.Time
+	Items    []string
+}
+
+var (
+	// This abstraction allows us to override when testing
+	bootTime = systemBootTime
+
+	// mu guards the marker while items are installed in parallel
+	mu sync.Mutex
+)
+
+// read returns the marker in `appDataPath`, or nil if there is none or the system has rebooted since it was written
+func read(appDataPath string) (*marker, error) {
+	path := filepath.Join(appDataPath, MarkerFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m marker
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	// A later boot time means the reboot has happened, so the marker is no longer needed
+	if booted, err := bootTime(); err == nil && booted.Sub(m.BootTime) > bootTolerance {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return &m, nil
+}
+
+// Require records that `item` needs a reboot to finish
+func Require(appDataPath, item string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	m, err := read(appDataPath)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		m = &marker{}
+		if m.BootTime, err = bootTime(); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(m.Items, item) {
+		m.Items = append(m.Items, item)
+	}
+
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(appDataPath, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(appDataPath, MarkerFile), data, 0644)
+}
+
+// Pending returns the items waiting for a reboot, or nil if no reboot is pending
+func Pending(appDataPath string) ([]string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	m, err := read(appDataPath)
+	if m == nil || err != nil {
+		return nil, err
+	}
+	return m.Items, nil
+}
+
+// IsPending returns true if a reboot is pending. If the state can not be read, it is assumed there is no reboot pending.
+func IsPending(appDataPath string) bool {
+	items, _ := Pending(appDataPath)
+	return len(items) > 0
+}
@@ -0,0 +1,61 @@
+package reboot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestPending verifies a reboot stays pending until the system boots again
+func TestPending(t *testing.T) {
+	appDataPath := t.TempDir()
+	booted := time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC)
+	origBootTime := bootTime
+	defer func() { bootTime = origBootTime }()
+	bootTime = func() (time.Time, error) { return booted, nil }
+
+	if IsPending(appDataPath) {
+		t.Errorf("Expected no reboot to be pending")
+	}
+
+	for _, item := range []string{"Firefox", "VisualCRedist", "Firefox"} {
+		if err := Require(appDataPath, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := Pending(appDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := items, []string{"Firefox", "VisualCRedist"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// A slightly different boot time is the same boot
+	booted = booted.Add(2 * time.Second)
+	if !IsPending(appDataPath) {
+		t.Errorf("Expected the reboot to still be pending")
+	}
+
+	// After a reboot the marker is removed
+	booted = booted.Add(time.Hour)
+	if IsPending(appDataPath) {
+		t.Errorf("Expected the reboot to be finished")
+	}
+	if _, err := os.Stat(filepath.Join(appDataPath, MarkerFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the marker to be removed")
+	}
+}
+
+// TestSystemBootTime verifies the boot time is in the past
+func TestSystemBootTime(t *testing.T) {
+	booted, err := systemBootTime()
+	if err != nil {
+		t.Skip("Boot time is not available:", err)
+	}
+	if !booted.Before(time.Now()) {
+		t.Errorf("Expected the boot time %s to be in the past", booted)
+	}
+}
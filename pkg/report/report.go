@@ -6,93 +6,270 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
-var (
-	// Items contains the data we will save to GorillaReport
-	Items = make(map[string]interface{})
+const (
+	// fileName is the name of the most recent report
+	fileName = "GorillaReport.json"
 
-	// InstalledItems contains a list of items we attempted to install
-	InstalledItems []interface{}
+	// historyDir is the directory under the app data path that stores previous reports
+	historyDir = "reports"
 
-	// UninstalledItems contains a list of items we attempted to uninstall
-	UninstalledItems []interface{}
+	// historyTimeFormat sorts lexically in the same order as the reports were written
+	historyTimeFormat = "20060102T150405.000Z"
+)
 
-	// fakeTime is used to override currentTime when running tests
-	fakeTime time.Time
+// Actions recorded in an `ItemResult`
+const (
+	ActionInstall   = "install"
+	ActionUpdate    = "update"
+	ActionUninstall = "uninstall"
 )
 
-// Start adds the data we already know at the beginning of a run
-func Start() {
+// Outcomes recorded in an `ItemResult`
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeNotNeeded = "not_needed"
+	OutcomeCheckOnly = "check_only"
+	OutcomeBlocked   = "blocked"
+	OutcomeDeferred  = "deferred"
+	OutcomeTimedOut  = "timed_out"
+)
+
+// ItemResult contains the result of processing a single item
+type ItemResult struct {
+	Name            string
+	DisplayName     string
+	Version         string
+	Action          string
+	Outcome         string
+	ExitCode        int
+	DurationSeconds float64
+	Error           string         `json:",omitempty"`
+	OutputTail      string         `json:",omitempty"`
+	CheckMethod     string         `json:",omitempty"`
+	RebootRequired  bool           `json:",omitempty"`
+	Scripts         []ScriptResult `json:",omitempty"`
+}
+
+// ScriptResult contains the exit code and the end of the output of a script run for an item
+type ScriptResult struct {
+	Phase    string
+	ExitCode int
+	Stdout   string `json:",omitempty"`
+	Stderr   string `json:",omitempty"`
+}
+
+// RunReport contains the data we will save to GorillaReport
+type RunReport struct {
+	StartTime      string
+	EndTime        string
+	CurrentUser    string
+	HostName       string
+	Manifest       string
+	Catalogs       []string
+	Items          []ItemResult
+	Errors         []string
+	RebootRequired bool `json:",omitempty"`
 
-	// Get the current time
-	currentTime := time.Now().UTC()
+	// mu guards Items and Errors while a run is in progress
+	mu sync.Mutex
+}
 
+var (
+	// fakeTime is used to override currentTime when running tests
+	fakeTime time.Time
+)
+
+// now returns the current time, or `fakeTime` while testing
+func now() time.Time {
 	// If fakeTime is not zero, we should use it instead
 	if !fakeTime.IsZero() {
-		currentTime = fakeTime
+		return fakeTime
 	}
+	return time.Now().UTC()
+}
 
-	// Add the end time to our map
-	Items["StartTime"] = fmt.Sprint(currentTime.Format("2006-01-02 15:04:05 -0700"))
+// currentTime returns the formatted current time
+func currentTime() string {
+	return now().Format("2006-01-02 15:04:05 -0700")
+}
+
+// New returns a report populated with the data we already know at the beginning of a run
+func New(manifest string, catalogs []string) *RunReport {
+	r := &RunReport{
+		StartTime: currentTime(),
+		Manifest:  manifest,
+		Catalogs:  catalogs,
+	}
 
 	// Store the current user
 	currentUser, userErr := user.Current()
 	if userErr != nil {
 		fmt.Println("Unable to determine current user", userErr)
+	} else {
+		r.CurrentUser = currentUser.Username
 	}
-	Items["CurrentUser"] = fmt.Sprint(currentUser.Username)
 
 	// Store the hostname
 	hostName, hostErr := os.Hostname()
 	if hostErr != nil {
-		fmt.Println("Unable to determine current time", hostErr)
+		fmt.Println("Unable to determine hostname", hostErr)
 	}
-	Items["HostName"] = fmt.Sprint(hostName)
+	r.HostName = hostName
+
+	return r
 }
 
-// End will compile everything and save to disk
-func End() {
+// Add records the result of processing an item. It is safe to call on a nil report.
+func (r *RunReport) Add(result ItemResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Items = append(r.Items, result)
+}
 
-	// Compile everything
-	Items["InstalledItems"] = InstalledItems
-	Items["UninstalledItems"] = UninstalledItems
+// AddError records a problem with the run that is not tied to a single item result.
+// It is safe to call on a nil report.
+func (r *RunReport) AddError(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Errors = append(r.Errors, err.Error())
+}
 
-	// Get the current time
-	currentTime := time.Now().UTC()
+// Path returns the location of the most recent report in `appDataPath`
+func Path(appDataPath string) string {
+	return filepath.Join(appDataPath, fileName)
+}
 
-	// If fakeTime is not zero, we should use it instead
-	if !fakeTime.IsZero() {
-		currentTime = fakeTime
-	}
+// End will compile everything and save to disk under `appDataPath`.
+// A timestamped copy is kept in the history directory, which is pruned to the newest `history` reports.
+// A `history` below 1 keeps no copies.
+func (r *RunReport) End(appDataPath string, history int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	// Add the end time to our map
-	Items["EndTime"] = fmt.Sprint(currentTime.Format("2006-01-02 15:04:05 -0700"))
+	// Add the end time
+	endTime := now()
+	r.EndTime = endTime.Format("2006-01-02 15:04:05 -0700")
 
 	// Convert it all to json
-	reportJSON, marshalErr := json.Marshal(Items)
+	reportJSON, marshalErr := json.Marshal(r)
 	if marshalErr != nil {
 		fmt.Println("Unable to create GorillaReport json", marshalErr)
+		return
 	}
 
-	// Write Items to disk as GorillaReport.json
-	reportPath := filepath.Join(os.Getenv("ProgramData"), "gorilla/GorillaReport.json")
-	writeErr := os.WriteFile(reportPath, reportJSON, 0644)
+	// Write the report to disk as GorillaReport.json
+	if err := os.MkdirAll(appDataPath, 0755); err != nil {
+		fmt.Println("Unable to create app data directory:", err)
+		return
+	}
+	writeErr := os.WriteFile(Path(appDataPath), reportJSON, 0644)
 	if writeErr != nil {
 		fmt.Println("Unable to write GorillaReport.json to disk:", writeErr)
 	}
 
+	if history < 1 {
+		return
+	}
+
+	// Keep a copy in the history directory
+	historyPath := filepath.Join(appDataPath, historyDir)
+	if err := os.MkdirAll(historyPath, 0755); err != nil {
+		fmt.Println("Unable to create report history directory:", err)
+		return
+	}
+	historyFile := filepath.Join(historyPath, "GorillaReport-"+endTime.Format(historyTimeFormat)+".json")
+	if err := os.WriteFile(historyFile, reportJSON, 0644); err != nil {
+		fmt.Println("Unable to write report history to disk:", err)
+		return
+	}
+
+	// Remove anything older than the newest `history` reports
+	reports, err := History(appDataPath)
+	if err != nil {
+		fmt.Println("Unable to read report history:", err)
+		return
+	}
+	for _, oldReport := range reports[min(history, len(reports)):] {
+		if err := os.Remove(oldReport); err != nil {
+			fmt.Println("Unable to remove old report:", err)
+		}
+	}
+}
+
+// History returns the paths of the reports saved under `appDataPath`, newest first
+func History(appDataPath string) ([]string, error) {
+	return listReports(filepath.Join(appDataPath, historyDir))
+}
+
+// listReports returns the paths of the timestamped reports in `dir`, newest first
+func listReports(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "GorillaReport-") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		reports = append(reports, filepath.Join(dir, name))
+	}
+
+	// The timestamp in each name sorts oldest first, so reverse it
+	slices.Sort(reports)
+	slices.Reverse(reports)
+	return reports, nil
+}
+
+// Load reads a report that was previously saved to disk
+func Load(path string) (*RunReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r RunReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("unable to parse report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Count returns the number of item results with each outcome
+func (r *RunReport) Count() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int)
+	for _, item := range r.Items {
+		counts[item.Outcome]++
+	}
+	return counts
 }
 
 // Print writes the report to stdout instead of writing to disk
 // Used in check only mode
-func Print() {
-	// Compile everything
-	Items["InstalledItems"] = InstalledItems
-	Items["UninstalledItems"] = UninstalledItems
+func (r *RunReport) Print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	reportJSON, marshalErr := json.MarshalIndent(Items, "", "    ")
+	reportJSON, marshalErr := json.MarshalIndent(r, "", "    ")
 	fmt.Println(string(reportJSON))
 	if marshalErr != nil {
 		fmt.Println("Unable to create GorillaReport json", marshalErr)
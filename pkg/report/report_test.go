@@ -1,80 +1,147 @@
 package report
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 )
 
-var (
-	expectedItems = make(map[string]interface{})
-)
-
-// TestStart validates that a properly formated `Items` object
-// is created with the expected starting data
-func TestStart(t *testing.T) {
+// TestNew validates that a report is created with the expected starting data
+func TestNew(t *testing.T) {
 
 	// Set our expectations
 	fakeTime = time.Now().UTC()
+	defer func() { fakeTime = time.Time{} }()
 	expectedTime := fakeTime.Format("2006-01-02 15:04:05 -0700")
 
 	expectedUser, userErr := user.Current()
 	if userErr != nil {
-		fmt.Println("Unable to determine expected user", userErr)
+		t.Fatal("Unable to determine expected user", userErr)
 	}
 
 	expectedHostname, hostErr := os.Hostname()
 	if hostErr != nil {
-		fmt.Println("Unable to determine current time", hostErr)
+		t.Fatal("Unable to determine expected hostname", hostErr)
 	}
 
-	// Put our expectations in a map for comparison
-	expectedItems["StartTime"] = fmt.Sprint(expectedTime)
-
-	expectedItems["CurrentUser"] = fmt.Sprint(expectedUser.Username)
-
-	expectedItems["HostName"] = fmt.Sprint(expectedHostname)
+	// Run the `New` function
+	r := New("example_manifest", []string{"production"})
 
-	// Run the `Start` function
-	Start()
-
-	// Compare the actual struct of items to what we expected
-	mapsMatch := reflect.DeepEqual(expectedItems, Items)
-
-	if !mapsMatch {
-		t.Errorf("\n\nExpected:\n\n%#v\n\nReceived:\n\n %#v", expectedItems, Items)
+	// Compare the actual report to what we expected
+	if have, want := r.StartTime, expectedTime; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := r.CurrentUser, expectedUser.Username; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := r.HostName, expectedHostname; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := r.Manifest, "example_manifest"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if have, want := r.Catalogs, []string{"production"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
 	}
 }
 
-// TestEnd validates that a properly formated `Items` object
-// is updated with the correct items and
+// TestEnd validates that results are kept and the end time is set
 func TestEnd(t *testing.T) {
 
 	// Set our expectations
 	fakeTime = time.Now().UTC()
+	defer func() { fakeTime = time.Time{} }()
 	expectedTime := fakeTime.Format("2006-01-02 15:04:05 -0700")
-	expectedInstalls := []interface{}{"test Installs 1", "test Installs 2", "test Installs 3", "test Installs 4"}
-	expectedUninstalls := []interface{}{"test Uninstalls 1", "test Uninstalls 2", "test Uninstalls 3", "test Uninstalls 4"}
-
-	// Apend everything tp the correct lists
-	InstalledItems = append(InstalledItems, expectedInstalls...)
-	UninstalledItems = append(UninstalledItems, expectedUninstalls...)
+	expectedItems := []ItemResult{
+		{Name: "ChefClient", Action: ActionInstall, Outcome: OutcomeSucceeded},
+		{Name: "Firefox", Action: ActionUninstall, Outcome: OutcomeFailed, ExitCode: 1603, Error: "exit status 1603"},
+	}
 
-	// Update the existing map for comparison
-	expectedItems["EndTime"] = fmt.Sprint(expectedTime)
-	expectedItems["InstalledItems"] = InstalledItems
-	expectedItems["UninstalledItems"] = UninstalledItems
+	r := New("example_manifest", nil)
+	for _, item := range expectedItems {
+		r.Add(item)
+	}
+	r.AddError(errors.New("dependency cycle detected: A -> B -> A"))
 
 	// Run the `End` function
-	End()
+	dir := t.TempDir()
+	r.End(dir, 5)
 
 	// Compare the actual results
-	mapsMatch := reflect.DeepEqual(expectedItems, Items)
+	if have, want := r.EndTime, expectedTime; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if !reflect.DeepEqual(expectedItems, r.Items) {
+		t.Errorf("\n\nExpected:\n\n%#v\n\nReceived:\n\n %#v", expectedItems, r.Items)
+	}
+	if have, want := r.Errors, []string{"dependency cycle detected: A -> B -> A"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
 
-	if !mapsMatch {
-		t.Errorf("\n\nExpected:\n\n%#v\n\nReceived:\n\n %#v", expectedItems, Items)
+	// The saved report should match what we recorded
+	saved, err := Load(Path(dir))
+	if err != nil {
+		t.Fatal(err)
 	}
+	if !reflect.DeepEqual(expectedItems, saved.Items) {
+		t.Errorf("\n\nExpected:\n\n%#v\n\nReceived:\n\n %#v", expectedItems, saved.Items)
+	}
+}
+
+// TestHistory verifies that previous reports are kept newest first and pruned
+func TestHistory(t *testing.T) {
+	defer func() { fakeTime = time.Time{} }()
+	dir := t.TempDir()
+
+	// Write five reports, one minute apart, keeping three
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		fakeTime = start.Add(time.Duration(i) * time.Minute)
+		r := New(fmt.Sprintf("manifest_%d", i), nil)
+		r.End(dir, 3)
+	}
+
+	reports, err := History(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(reports), 3; have != want {
+		t.Fatalf("have %d reports, want %d: %v", have, want, reports)
+	}
+
+	// The newest report should be first
+	for i, expected := range []string{"manifest_4", "manifest_3", "manifest_2"} {
+		r, err := Load(reports[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := r.Manifest, expected; have != want {
+			t.Errorf("report %d: have %s, want %s", i+1, have, want)
+		}
+	}
+}
+
+// TestEndWithoutHistory verifies the app data directory is created and no history is kept when it is turned off
+func TestEndWithoutHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	New("example_manifest", nil).End(dir, -1)
+
+	if _, err := os.Stat(Path(dir)); err != nil {
+		t.Errorf("Expected the report to be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, historyDir)); !os.IsNotExist(err) {
+		t.Errorf("Expected no report history, got: %v", err)
+	}
+}
+
+// TestNilReport verifies results can be recorded without a report
+func TestNilReport(t *testing.T) {
+	var r *RunReport
+	r.Add(ItemResult{Name: "ChefClient"})
+	r.AddError(errors.New("ignored"))
 }
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/1dustindavis/gorilla/pkg/download"
+)
+
+// spoolDir is the directory under the app data path that stores reports waiting to be uploaded
+const spoolDir = "spool"
+
+// This abstraction allows us to override when testing
+var downloadPost = download.Post
+
+// Upload sends any previously spooled reports, followed by this report, to `reportURL`.
+// Reports are spooled under `appDataPath` until they are accepted, so anything that fails
+// is retried on the next run. At most `keep` reports are kept in the spool.
+func (r *RunReport) Upload(reportURL, appDataPath string, keep int) error {
+	r.mu.Lock()
+	reportJSON, err := json.Marshal(r)
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("unable to create report json: %w", err)
+	}
+
+	// Add this report to the spool before trying to send anything
+	spoolPath := filepath.Join(appDataPath, spoolDir)
+	if err := os.MkdirAll(spoolPath, 0755); err != nil {
+		return fmt.Errorf("unable to create report spool: %w", err)
+	}
+	spoolFile := filepath.Join(spoolPath, "GorillaReport-"+now().Format(historyTimeFormat)+".json")
+	if err := os.WriteFile(spoolFile, reportJSON, 0644); err != nil {
+		return fmt.Errorf("unable to spool report: %w", err)
+	}
+
+	spooled, err := listReports(spoolPath)
+	if err != nil {
+		return fmt.Errorf("unable to read report spool: %w", err)
+	}
+
+	// Drop the oldest reports if the collector has been unreachable for a while
+	if keep > 0 && len(spooled) > keep {
+		for _, oldReport := range spooled[keep:] {
+			if err := os.Remove(oldReport); err != nil {
+				return fmt.Errorf("unable to remove old spooled report: %w", err)
+			}
+		}
+		spooled = spooled[:keep]
+	}
+
+	// Send the oldest reports first, stopping at the first failure so the order is kept
+	slices.Reverse(spooled)
+	for _, path := range spooled {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read spooled report: %w", err)
+		}
+		if err := downloadPost(reportURL, "application/json", body); err != nil {
+			return fmt.Errorf("unable to upload report %s: %w", filepath.Base(path), err)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("unable to remove uploaded report: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+	"github.com/1dustindavis/gorilla/pkg/download"
+)
+
+// TestUploadSpoolsFailures verifies that a failed upload is kept and sent on the next run
+func TestUploadSpoolsFailures(t *testing.T) {
+	defer func() { fakeTime = time.Time{} }()
+	download.SetConfig(config.Configuration{})
+
+	// The collector is down for the first run
+	available := false
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var rpt RunReport
+		if err := json.Unmarshal(body, &rpt); err != nil {
+			t.Errorf("collector received invalid json: %v", err)
+		}
+		received = append(received, rpt.Manifest)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	fakeTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := New("first_run", nil).Upload(ts.URL, dir, 5); err == nil {
+		t.Fatalf("expected the first upload to fail")
+	}
+	if spooled, _ := listReports(filepath.Join(dir, spoolDir)); len(spooled) != 1 {
+		t.Fatalf("expected one spooled report, got %v", spooled)
+	}
+
+	// The collector is back for the second run, both reports should be sent in order
+	available = true
+	fakeTime = fakeTime.Add(time.Hour)
+	if err := New("second_run", nil).Upload(ts.URL, dir, 5); err != nil {
+		t.Fatalf("unexpected upload error: %v", err)
+	}
+	if len(received) != 2 || received[0] != "first_run" || received[1] != "second_run" {
+		t.Errorf("unexpected uploads: %v", received)
+	}
+	if spooled, _ := listReports(filepath.Join(dir, spoolDir)); len(spooled) != 0 {
+		t.Errorf("expected the spool to be empty, got %v", spooled)
+	}
+}
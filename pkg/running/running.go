@@ -0,0 +1,43 @@
+package running
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Process is a process running on the system
+type Process struct {
+	PID  int
+	Name string // the file name of the executable, like "chrome.exe"
+}
+
+// Lister finds and stops running processes. It is an interface so tests can use a fake.
+type Lister interface {
+	Processes() ([]Process, error)
+	Terminate(pid int) error
+}
+
+// System lists the processes running on this computer
+type System struct{}
+
+// Match returns the processes whose executable matches one of `apps`.
+// Apps are compared by file name without regard to case, and the ".exe" extension is optional.
+func Match(processes []Process, apps []string) []Process {
+	var matched []Process
+	for _, process := range processes {
+		name := normalize(process.Name)
+		for _, app := range apps {
+			if app != "" && name == normalize(app) {
+				matched = append(matched, process)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// normalize returns the lower case file name of an executable without ".exe"
+func normalize(name string) string {
+	name = strings.ToLower(filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), `\`, "/")))
+	return strings.TrimSuffix(name, ".exe")
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package running
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Processes returns every running process listed in /proc
+func (System) Processes() ([]Process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var processes []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		// The command line has the full name, `comm` is cut off after 15 characters
+		name := ""
+		if cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline")); err == nil {
+			name = string(bytes.SplitN(cmdline, []byte{0}, 2)[0])
+		}
+		if name == "" {
+			comm, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm"))
+			if err != nil {
+				// The process has exited
+				continue
+			}
+			name = strings.TrimSpace(string(comm))
+		}
+		processes = append(processes, Process{PID: pid, Name: filepath.Base(name)})
+	}
+	return processes, nil
+}
+
+// Terminate stops a process immediately
+func (System) Terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
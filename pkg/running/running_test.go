@@ -0,0 +1,46 @@
+package running
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestMatch verifies apps are matched by file name, with or without ".exe"
+func TestMatch(t *testing.T) {
+	processes := []Process{
+		{PID: 1, Name: "chrome.exe"},
+		{PID: 2, Name: "Chrome.EXE"},
+		{PID: 3, Name: "firefox.exe"},
+		{PID: 4, Name: "teams"},
+		{PID: 5, Name: "chromedriver.exe"},
+	}
+	expected := []Process{
+		{PID: 1, Name: "chrome.exe"},
+		{PID: 2, Name: "Chrome.EXE"},
+		{PID: 4, Name: "teams"},
+	}
+	actual := Match(processes, []string{"chrome", `C:\Program Files\Teams\Teams.exe`, ""})
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
+
+// TestSystemProcesses verifies the test itself is found among the running processes
+func TestSystemProcesses(t *testing.T) {
+	processes, err := System{}.Processes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, process := range processes {
+		if process.PID == os.Getpid() && Match([]Process{process}, []string{filepath.Base(self)}) != nil {
+			return
+		}
+	}
+	t.Errorf("Expected to find process %d (%s)", os.Getpid(), filepath.Base(self))
+}
@@ -0,0 +1,172 @@
+package script
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/command"
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+)
+
+// Interpreters that can be chosen by name. Any other interpreter is treated as
+// the path to an executable, optionally followed by its arguments.
+const (
+	PowerShell = "powershell"
+	Pwsh       = "pwsh"
+	Cmd        = "cmd"
+	Sh         = "sh"
+	Bash       = "bash"
+)
+
+// Phases a script can run in
+const (
+	PhaseCheck         = "check"
+	PhasePreinstall    = "preinstall"
+	PhaseInstall       = "install"
+	PhasePostinstall   = "postinstall"
+	PhasePreuninstall  = "preuninstall"
+	PhaseUninstall     = "uninstall"
+	PhasePostuninstall = "postuninstall"
+)
+
+// psArgs are passed to PowerShell before the script file
+var psArgs = []string{"-NoProfile", "-NoLogo", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"}
+
+// Script is a catalog script and the item it runs for
+type Script struct {
+	Body string
+	// Interpreter runs the script, PowerShell if it is empty. A first line starting with
+	// "#!" in `Body` takes precedence.
+	Interpreter string
+	Phase       string
+	Action      string
+	Item        catalog.Item
+	CachePath   string
+	Timeout     time.Duration
+	// Command builds the command to run, it is `exec.Command` if nil
+	Command func(name string, arg ...string) *exec.Cmd
+}
+
+// Result is the output and exit code of a script
+type Result struct {
+	Phase    string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// Run writes the script to a unique file in the cache and runs it with its interpreter.
+// The error is the command's error, so a script that exits with a non-zero code returns
+// an error with an exit code. If the script did not finish, the exit code is -1.
+func Run(s Script) (Result, error) {
+	result := Result{Phase: s.Phase, ExitCode: -1}
+
+	name, args, ext := interpreter(s.Body, s.Interpreter)
+	tmpScript, err := writeScript(s, ext)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		if err := os.Remove(tmpScript); err != nil && !os.IsNotExist(err) {
+			gorillalog.Warn("Unable to remove temporary", s.Phase, "script:", tmpScript, err)
+		}
+	}()
+
+	execCommand := s.Command
+	if execCommand == nil {
+		execCommand = exec.Command
+	}
+	cmd := execCommand(name, slices.Concat(args, []string{tmpScript})...)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, environment(s)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Execute the script
+	gorillalog.Debug("command:", name, args, tmpScript)
+	err = command.Run(cmd, s.Timeout)
+	if cmd.ProcessState != nil && !errors.Is(err, command.ErrTimeout) {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+
+	// Log results
+	gorillalog.Debug("Command Error:", err)
+	gorillalog.Debug("stdout:", result.Stdout)
+	gorillalog.Debug("stderr:", result.Stderr)
+
+	return result, err
+}
+
+// writeScript saves the script body to a new file in the cache, so scripts never share a file
+func writeScript(s Script, ext string) (string, error) {
+	if err := os.MkdirAll(s.CachePath, 0755); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(s.CachePath, fmt.Sprintf("gorilla-%s-*%s", s.Phase, ext))
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(s.Body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// interpreter returns the command and arguments that run a script, and the extension its file needs
+func interpreter(body, name string) (string, []string, string) {
+	name = strings.TrimSpace(name)
+	if line, _, _ := strings.Cut(body, "\n"); strings.HasPrefix(line, "#!") {
+		name = strings.TrimSpace(strings.TrimPrefix(line, "#!"))
+	}
+
+	// A blank interpreter falls back to PowerShell
+	switch strings.ToLower(name) {
+	case "", PowerShell:
+		return filepath.Join(os.Getenv("WINDIR"), "system32", "WindowsPowershell", "v1.0", "powershell.exe"), psArgs, ".ps1"
+	case Pwsh:
+		return "pwsh", psArgs, ".ps1"
+	case Cmd:
+		return filepath.Join(os.Getenv("WINDIR"), "system32", "cmd.exe"), []string{"/c"}, ".cmd"
+	case Sh:
+		return "sh", nil, ".sh"
+	case Bash:
+		return "bash", nil, ".sh"
+	}
+
+	// A path to an executable, which may contain spaces, or an executable followed by its arguments
+	if _, err := os.Stat(name); err == nil {
+		return name, nil, ""
+	}
+	fields := strings.Fields(name)
+	return fields[0], fields[1:], ""
+}
+
+// environment returns the variables that describe the item to the script
+func environment(s Script) []string {
+	return []string{
+		"GORILLA_ITEM_NAME=" + s.Item.Name,
+		"GORILLA_DISPLAY_NAME=" + s.Item.DisplayName,
+		"GORILLA_ITEM_VERSION=" + s.Item.Version,
+		"GORILLA_ACTION=" + s.Action,
+		"GORILLA_SCRIPT_PHASE=" + s.Phase,
+		"GORILLA_CACHE_PATH=" + s.CachePath,
+	}
+}
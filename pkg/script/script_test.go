@@ -0,0 +1,101 @@
+package script
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/catalog"
+)
+
+// requireSh skips a test that runs real scripts when sh is not available
+func requireSh(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+}
+
+// TestInterpreter verifies the command used for each interpreter
+func TestInterpreter(t *testing.T) {
+	t.Setenv("WINDIR", `C:\Windows`)
+	tests := []struct {
+		body        string
+		interpreter string
+		command     string
+		args        []string
+		ext         string
+	}{
+		{interpreter: "", command: filepath.Join(`C:\Windows`, "system32", "WindowsPowershell", "v1.0", "powershell.exe"), args: psArgs, ext: ".ps1"},
+		{interpreter: " \t", command: filepath.Join(`C:\Windows`, "system32", "WindowsPowershell", "v1.0", "powershell.exe"), args: psArgs, ext: ".ps1"},
+		{interpreter: " bash ", command: "bash", ext: ".sh"},
+		{interpreter: "PWSH", command: "pwsh", args: psArgs, ext: ".ps1"},
+		{interpreter: "cmd", command: filepath.Join(`C:\Windows`, "system32", "cmd.exe"), args: []string{"/c"}, ext: ".cmd"},
+		{interpreter: "bash", command: "bash", ext: ".sh"},
+		{interpreter: "python3 -u", command: "python3", args: []string{"-u"}},
+		{body: "#!/usr/bin/env python3\nprint('hi')", interpreter: "pwsh", command: "/usr/bin/env", args: []string{"python3"}},
+		{body: "#! sh\necho hi", command: "sh", ext: ".sh"},
+	}
+	for _, tt := range tests {
+		command, args, ext := interpreter(tt.body, tt.interpreter)
+		if command != tt.command || !reflect.DeepEqual(args, tt.args) || ext != tt.ext {
+			t.Errorf("%q %q: have %s %v %q, want %s %v %q", tt.interpreter, tt.body, command, args, ext, tt.command, tt.args, tt.ext)
+		}
+	}
+}
+
+// TestRun verifies a script gets the item's environment and its output and exit code are captured
+func TestRun(t *testing.T) {
+	requireSh(t)
+	cachePath := t.TempDir()
+
+	result, err := Run(Script{
+		Body:        "echo \"$GORILLA_ITEM_NAME $GORILLA_ITEM_VERSION $GORILLA_ACTION $GORILLA_SCRIPT_PHASE\"\necho oops >&2\nexit 3",
+		Interpreter: Sh,
+		Phase:       PhasePreinstall,
+		Action:      "install",
+		Item:        catalog.Item{Name: "Firefox", Version: "1.2.3"},
+		CachePath:   cachePath,
+	})
+	if err == nil {
+		t.Errorf("Expected an error for a non-zero exit code")
+	}
+	expected := Result{Phase: PhasePreinstall, ExitCode: 3, Stdout: "Firefox 1.2.3 install preinstall\n", Stderr: "oops\n"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("\nExpected: %#v\nActual:   %#v", expected, result)
+	}
+
+	// The temporary script is removed
+	entries, err := os.ReadDir(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the temporary script to be removed, found %v", entries)
+	}
+}
+
+// TestRunUniqueFiles verifies scripts running at the same time do not share a file
+func TestRunUniqueFiles(t *testing.T) {
+	requireSh(t)
+	cachePath := t.TempDir()
+
+	// Each script prints the file it was run from
+	paths := make(chan string, 2)
+	for range 2 {
+		go func() {
+			result, err := Run(Script{Body: `echo "$0"; sleep 0.2`, Interpreter: Sh, Phase: PhaseCheck, CachePath: cachePath})
+			if err != nil {
+				t.Error(err)
+			}
+			paths <- strings.TrimSpace(result.Stdout)
+		}()
+	}
+	first, second := <-paths, <-paths
+	if first == second || !strings.HasPrefix(filepath.Base(first), "gorilla-check-") {
+		t.Errorf("Expected two unique script files, have %s and %s", first, second)
+	}
+}
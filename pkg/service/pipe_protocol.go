@@ -72,6 +72,7 @@ type operationStatusEventPayload struct {
 	ErrorCode       string `json:"errorCode,omitempty"`
 	ErrorMessage    string `json:"errorMessage,omitempty"`
 	CanceledBy      string `json:"canceledBy,omitempty"`
+	RebootRequired  bool   `json:"rebootRequired,omitempty"`
 }
 
 type errorResponsePayload struct {
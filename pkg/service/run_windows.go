@@ -18,6 +18,7 @@ import (
 
 	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/reboot"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc"
 )
@@ -385,6 +386,7 @@ func (sr *serviceRunner) scheduleRunAfterMutation(ctx context.Context, action, o
 				Message:         "Operation failed",
 				ErrorCode:       "managed_run_failed",
 				ErrorMessage:    err.Error(),
+				RebootRequired:  reboot.IsPending(sr.cfg.AppDataPath),
 			})
 			return
 		}
@@ -392,6 +394,7 @@ func (sr *serviceRunner) scheduleRunAfterMutation(ctx context.Context, action, o
 			State:           "Succeeded",
 			ProgressPercent: 100,
 			Message:         "Operation completed",
+			RebootRequired:  reboot.IsPending(sr.cfg.AppDataPath),
 		})
 	}()
 }
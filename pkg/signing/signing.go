@@ -0,0 +1,138 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+)
+
+// Extension is added to the name of a file to get the name of its detached signature
+const Extension = ".sig"
+
+// ErrInvalidSignature is returned when a signature does not match any trusted key
+var ErrInvalidSignature = errors.New("signature does not match any trusted public key")
+
+// LoadPrivateKey reads an Ed25519 private key from a PKCS #8 PEM file
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(pemData)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s does not contain a PEM encoded private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key %s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKeys reads Ed25519 public keys from PKIX PEM files. Each file may contain more than one key.
+func LoadPublicKeys(paths []string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, path := range paths {
+		rest, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "PUBLIC KEY" {
+				continue
+			}
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse public key %s: %w", path, err)
+			}
+			publicKey, ok := key.(ed25519.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+			}
+			keys = append(keys, publicKey)
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("%s does not contain a PEM encoded public key", path)
+		}
+	}
+	return keys, nil
+}
+
+// Sign returns a detached, base64 encoded signature of `data`
+func Sign(key ed25519.PrivateKey, data []byte) []byte {
+	signature := ed25519.Sign(key, data)
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+}
+
+// Verify checks a detached signature created by `Sign` against each trusted key
+func Verify(keys []ed25519.PublicKey, data, signature []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("unable to decode signature: %w", err)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, data, decoded) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Verifier checks downloaded manifests and catalogs against the trusted keys in the config
+type Verifier struct {
+	keys    []ed25519.PublicKey
+	require bool
+}
+
+// NewVerifier loads the trusted public keys from the config. Signatures are required when
+// `require_signatures` is set or any trusted keys are configured, otherwise deleting a signature
+// from the server would be enough to skip verification.
+func NewVerifier(cfg config.Configuration) (*Verifier, error) {
+	keys, err := LoadPublicKeys(cfg.SigningPublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load signing keys: %w", err)
+	}
+	return &Verifier{keys: keys, require: cfg.RequireSignatures || len(keys) > 0}, nil
+}
+
+// VerifyURL downloads the detached signature of `url` with `get` and checks it against `data`.
+// If no trusted keys are configured, nothing is checked unless signatures are required.
+// Once keys are configured, a missing or mismatched signature is always an error.
+func (v *Verifier) VerifyURL(url string, data []byte, get func(string) ([]byte, error)) error {
+	if len(v.keys) == 0 {
+		if v.require {
+			return fmt.Errorf("%s : Signatures are required but no public keys are configured", url)
+		}
+		return nil
+	}
+
+	signature, err := get(url + Extension)
+	if err != nil {
+		return fmt.Errorf("%s : Unable to retrieve required signature: %w", url, err)
+	}
+
+	if err := Verify(v.keys, data, signature); err != nil {
+		return fmt.Errorf("%s : %w", url, err)
+	}
+	gorillalog.Debug("Verified signature:", url)
+	return nil
+}
@@ -0,0 +1,157 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/1dustindavis/gorilla/pkg/config"
+)
+
+// writeKeys generates a key pair and saves it as PEM files in `dir`
+func writeKeys(t *testing.T, dir string) (privatePath, publicPath string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privatePath = filepath.Join(dir, "signing.key")
+	publicPath = filepath.Join(dir, "signing.pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+// TestSignVerify verifies that signatures made with a private key match its public key
+func TestSignVerify(t *testing.T) {
+	privatePath, publicPath := writeKeys(t, t.TempDir())
+	otherDir := t.TempDir()
+	_, otherPublicPath := writeKeys(t, otherDir)
+
+	privateKey, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadPublicKeys([]string{otherPublicPath, publicPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("managed_installs:\n  - GoogleChrome\n")
+	signature := Sign(privateKey, data)
+
+	// Any of the trusted keys can match
+	if err := Verify(keys, data, signature); err != nil {
+		t.Errorf("Expected a valid signature, got: %v", err)
+	}
+
+	// Changed data should not match
+	if err := Verify(keys, []byte("managed_installs: []\n"), signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got: %v", err)
+	}
+
+	// Neither should a key we do not trust
+	otherKeys, err := LoadPublicKeys([]string{otherPublicPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(otherKeys, data, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got: %v", err)
+	}
+}
+
+// TestVerifyURL verifies how missing and invalid signatures are handled
+func TestVerifyURL(t *testing.T) {
+	privatePath, publicPath := writeKeys(t, t.TempDir())
+	privateKey, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("example: catalog\n")
+	signatures := map[string][]byte{
+		"https://example.com/catalogs/signed.yaml.sig":   Sign(privateKey, data),
+		"https://example.com/catalogs/tampered.yaml.sig": Sign(privateKey, []byte("something else")),
+	}
+	fakeGet := func(url string) ([]byte, error) {
+		if signature, ok := signatures[url]; ok {
+			return signature, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	tests := []struct {
+		url     string
+		require bool
+		wantErr bool
+	}{
+		{url: "https://example.com/catalogs/signed.yaml", require: true, wantErr: false},
+		{url: "https://example.com/catalogs/tampered.yaml", require: false, wantErr: true},
+		// Configured keys make signatures mandatory, even when require_signatures is false
+		{url: "https://example.com/catalogs/unsigned.yaml", require: false, wantErr: true},
+		{url: "https://example.com/catalogs/unsigned.yaml", require: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		verifier, err := NewVerifier(config.Configuration{SigningPublicKeys: []string{publicPath}, RequireSignatures: tt.require})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = verifier.VerifyURL(tt.url, data, fakeGet)
+		if have, want := err != nil, tt.wantErr; have != want {
+			t.Errorf("%s (require %v): have error %v, want error %v", tt.url, tt.require, err, want)
+		}
+	}
+
+	// A signature the server does not have is an error once keys are configured
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	httpGet := func(url string) ([]byte, error) {
+		resp, err := http.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+	verifier, err := NewVerifier(config.Configuration{SigningPublicKeys: []string{publicPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.VerifyURL(ts.URL+"/catalogs/production.yaml", data, httpGet); err == nil {
+		t.Errorf("Expected an error when the signature returns 404")
+	}
+
+	// Without any keys, nothing is checked
+	verifier, err = NewVerifier(config.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.VerifyURL("https://example.com/catalogs/tampered.yaml", data, fakeGet); err != nil {
+		t.Errorf("Expected no error without keys, got: %v", err)
+	}
+}
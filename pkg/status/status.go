@@ -1,15 +1,18 @@
 package status
 
 import (
-	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/1dustindavis/gorilla/pkg/catalog"
+	"github.com/1dustindavis/gorilla/pkg/command"
+	"github.com/1dustindavis/gorilla/pkg/config"
 	"github.com/1dustindavis/gorilla/pkg/download"
 	"github.com/1dustindavis/gorilla/pkg/gorillalog"
+	"github.com/1dustindavis/gorilla/pkg/script"
 	version "github.com/hashicorp/go-version"
 )
 
@@ -34,7 +37,32 @@ type WindowsMetadata struct {
 	versionBuild  int
 }
 
+// Result describes the outcome of checking the status of a catalog item
+type Result struct {
+	// ActionNeeded is true when the requested install type should be performed
+	ActionNeeded bool
+	// Method is the type of check that was used: script, file, registry or none
+	Method string
+	// DetectedVersion is the currently installed version, if the check could determine it
+	DetectedVersion string
+	// Reason is a short human readable explanation of the result
+	Reason string
+	// Script is the output of the check script, if one was run
+	Script *script.Result
+}
+
+// Check methods reported in a `Result`
+const (
+	MethodScript   = "script"
+	MethodFile     = "file"
+	MethodRegistry = "registry"
+	MethodNone     = "none"
+)
+
 var (
+	// A package level copy of our config for the `status` package to reference
+	statusCfg config.Configuration
+
 	// RegistryItems contains the status of all of the applications in the registry
 	RegistryItems map[string]RegistryApplication
 
@@ -42,8 +70,15 @@ var (
 	execCommand = exec.Command
 )
 
+// SetConfig accepts a configuration struct that all functions in the `status` package will use
+func SetConfig(cfg config.Configuration) {
+	statusCfg = cfg
+}
+
 // checkRegistry iterates through the local registry and compiles all installed software
-func checkRegistry(catalogItem catalog.Item, installType string) (actionNeeded bool, checkErr error) {
+func checkRegistry(catalogItem catalog.Item, installType string) (result Result, checkErr error) {
+	result.Method = MethodRegistry
+
 	// Iterate through the reg keys to compare with the catalog
 	checkReg := catalogItem.Check.Registry
 	catalogVersion, err := version.NewVersion(checkReg.Version)
@@ -63,6 +98,7 @@ func checkRegistry(catalogItem catalog.Item, installType string) (actionNeeded b
 		// Check if the catalog name is in the registry
 		if strings.Contains(regItem.Name, checkReg.Name) {
 			installed = true
+			result.DetectedVersion = regItem.Version
 			gorillalog.Debug("Current installed version:", regItem.Version)
 
 			// Check if the catalog version matches the registry
@@ -80,65 +116,67 @@ func checkRegistry(catalogItem catalog.Item, installType string) (actionNeeded b
 	}
 
 	if installType == "update" && !installed {
-		actionNeeded = false
+		result.ActionNeeded = false
+		result.Reason = "not installed"
 	} else if installType == "uninstall" {
-		actionNeeded = installed
+		result.ActionNeeded = installed
+		if installed {
+			result.Reason = "installed"
+		} else {
+			result.Reason = "not installed"
+		}
 	} else if installed && versionMatch {
-		actionNeeded = false
+		result.ActionNeeded = false
+		result.Reason = "installed version is current"
+	} else if installed {
+		result.ActionNeeded = true
+		result.Reason = fmt.Sprintf("installed version %s is older than %s", result.DetectedVersion, checkReg.Version)
 	} else {
-		actionNeeded = true
+		result.ActionNeeded = true
+		result.Reason = "not installed"
 	}
 
-	return actionNeeded, checkErr
+	return result, checkErr
 }
 
-func checkScript(catalogItem catalog.Item, cachePath string, installType string) (actionNeeded bool, checkErr error) {
-	if err := os.MkdirAll(cachePath, 0755); err != nil {
-		return false, err
-	}
-
-	// Write InstallCheckScript to disk as a Powershell file
-	tmpScript := filepath.Join(cachePath, "tmpCheckScript.ps1")
-	if err := os.WriteFile(tmpScript, []byte(catalogItem.Check.Script), 0755); err != nil {
-		return false, err
+func checkScript(catalogItem catalog.Item, cachePath string, installType string) (result Result, checkErr error) {
+	result.Method = MethodScript
+
+	// Run the check script
+	scriptResult, err := script.Run(script.Script{
+		Body:        catalogItem.Check.Script,
+		Interpreter: catalogItem.ScriptInterpreter,
+		Phase:       script.PhaseCheck,
+		Action:      installType,
+		Item:        catalogItem,
+		CachePath:   cachePath,
+		Timeout:     command.Timeout(catalogItem.ScriptTimeout, statusCfg.ScriptTimeout),
+		Command:     execCommand,
+	})
+	result.Script = &scriptResult
+
+	// A check script that did not finish can not tell us anything
+	if scriptResult.ExitCode < 0 {
+		return result, err
 	}
+	cmdSuccess := scriptResult.ExitCode == 0
 
-	// Build the command to execute the script
-	psCmd := filepath.Join(os.Getenv("WINDIR"), "system32/", "WindowsPowershell", "v1.0", "powershell.exe")
-	psArgs := []string{"-NoProfile", "-NoLogo", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", tmpScript}
-
-	// Execute the script
-	cmd := execCommand(psCmd, psArgs...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	cmdSuccess := cmd.ProcessState.Success()
-	outStr, errStr := stdout.String(), stderr.String()
-
-	// Delete the temporary script
-	if err := os.Remove(tmpScript); err != nil && !os.IsNotExist(err) {
-		gorillalog.Warn("Unable to remove temporary check script:", tmpScript, err)
-	}
-
-	// Log results
-	gorillalog.Debug("Command Error:", err)
-	gorillalog.Debug("stdout:", outStr)
-	gorillalog.Debug("stderr:", errStr)
-
-	actionNeeded = false
+	result.ActionNeeded = false
 	// Application not installed if exit 0
 	if installType == "uninstall" {
-		actionNeeded = !cmdSuccess
+		result.ActionNeeded = !cmdSuccess
 	} else if installType == "install" || installType == "update" {
-		actionNeeded = cmdSuccess
+		result.ActionNeeded = cmdSuccess
 	}
+	result.Reason = fmt.Sprintf("check script exited with code %d", scriptResult.ExitCode)
 
-	return actionNeeded, checkErr
+	return result, checkErr
 }
 
-func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool, checkErr error) {
+func checkPath(catalogItem catalog.Item, installType string) (result Result, checkErr error) {
+	result.Method = MethodFile
 	var actionStore []bool
+	var reasons []string
 
 	// Iterate through all file provided paths
 	for _, checkFile := range catalogItem.Check.File {
@@ -152,6 +190,7 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 				// perform an install
 				if installType == "install" {
 					actionStore = append(actionStore, true)
+					reasons = append(reasons, "file not found: "+path)
 					break
 				}
 
@@ -159,10 +198,12 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 				// not exist, do nothing
 				if installType == "update" || installType == "uninstall" {
 					gorillalog.Debug("No action needed: Install type is", installType)
+					reasons = append(reasons, "file not found: "+path)
 					break
 				}
 			}
 			gorillalog.Warn("Unable to check path:", path, err)
+			reasons = append(reasons, "unable to check path: "+path)
 			break
 
 		} else if err == nil {
@@ -171,6 +212,7 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 			// perform uninstall
 			if installType == "uninstall" {
 				actionStore = append(actionStore, true)
+				reasons = append(reasons, "file exists: "+path)
 			}
 		}
 
@@ -181,6 +223,7 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 			hashMatch := download.Verify(path, checkFile.Hash)
 			if !hashMatch {
 				actionStore = append(actionStore, true)
+				reasons = append(reasons, "file hash does not match: "+path)
 				break
 			}
 		}
@@ -193,6 +236,7 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 			if metadata.versionString == "" {
 				break
 			}
+			result.DetectedVersion = metadata.versionString
 			gorillalog.Debug("Current installed version:", metadata.versionString)
 
 			// Convert both strings to a `Version` object
@@ -200,12 +244,14 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 			if err != nil {
 				gorillalog.Warn("Unable to compare version:", metadata.versionString)
 				actionStore = append(actionStore, true)
+				reasons = append(reasons, "unable to compare version: "+metadata.versionString)
 				break
 			}
 			versionWant, err := version.NewVersion(checkFile.Version)
 			if err != nil {
 				gorillalog.Warn("Unable to compare version:", checkFile.Version)
 				actionStore = append(actionStore, true)
+				reasons = append(reasons, "unable to compare version: "+checkFile.Version)
 				break
 			}
 
@@ -213,23 +259,29 @@ func checkPath(catalogItem catalog.Item, installType string) (actionNeeded bool,
 			outdated := versionHave.LessThan(versionWant)
 			if outdated {
 				actionStore = append(actionStore, true)
+				reasons = append(reasons, fmt.Sprintf("file version %s is older than %s: %s", metadata.versionString, checkFile.Version, path))
 				break
 			}
 		}
 	}
 
+	result.Reason = strings.Join(reasons, "; ")
+	if result.Reason == "" {
+		result.Reason = "all file checks passed"
+	}
+
 	for _, item := range actionStore {
 		if item {
-			actionNeeded = true
-			return
+			result.ActionNeeded = true
+			return result, checkErr
 		}
 	}
-	actionNeeded = false
-	return actionNeeded, checkErr
+	result.ActionNeeded = false
+	return result, checkErr
 }
 
-// CheckStatus determines the method for checking status
-func CheckStatus(catalogItem catalog.Item, installType, cachePath string) (actionNeeded bool, checkErr error) {
+// Check determines the method for checking status and returns the detailed result
+func Check(catalogItem catalog.Item, installType, cachePath string) (Result, error) {
 
 	if catalogItem.Check.Script != "" {
 		gorillalog.Info("Checking status via script:", catalogItem.DisplayName)
@@ -245,6 +297,12 @@ func CheckStatus(catalogItem catalog.Item, installType, cachePath string) (actio
 	}
 
 	gorillalog.Warn("Not enough data to check the current status:", catalogItem.DisplayName)
-	return
+	return Result{Method: MethodNone, Reason: "not enough data to check the current status"}, nil
+
+}
 
+// CheckStatus determines the method for checking status
+func CheckStatus(catalogItem catalog.Item, installType, cachePath string) (actionNeeded bool, checkErr error) {
+	result, err := Check(catalogItem, installType, cachePath)
+	return result.ActionNeeded, err
 }
@@ -185,62 +185,62 @@ func TestCheckRegistry(t *testing.T) {
 
 	// Run checkRegistry with `registryCheckItem` as an `install`
 	// We expect no action needed; Only error if action needed is true
-	actionNeeded, _ := checkRegistry(registryCheckItem, "install")
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", actionNeeded)
+	result, _ := checkRegistry(registryCheckItem, "install")
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", result.ActionNeeded)
 	}
 
 	// Run checkRegistry with `registryCheckItemNotInstalled` as an `install`
 	// We expect action is needed; Only error if action needed is false
-	actionNeeded, _ = checkRegistry(registryCheckItemNotInstalled, "install")
-	if !actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", actionNeeded)
+	result, _ = checkRegistry(registryCheckItemNotInstalled, "install")
+	if !result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", result.ActionNeeded)
 	}
 
 	// Run checkRegistry with `registryCheckItemOutdated` as an `install`
 	// We expect action is needed; Only error if action needed is false
-	actionNeeded, _ = checkRegistry(registryCheckItemOutdated, "install")
-	if !actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", actionNeeded)
+	result, _ = checkRegistry(registryCheckItemOutdated, "install")
+	if !result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", result.ActionNeeded)
 	}
 
 	// uninstall
 
 	// Run checkRegistry with `registryCheckItem` as an `uninstall`
 	// We expect action is needed; Only error if action needed is false
-	actionNeeded, _ = checkRegistry(registryCheckItem, "uninstall")
-	if !actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", actionNeeded)
+	result, _ = checkRegistry(registryCheckItem, "uninstall")
+	if !result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", result.ActionNeeded)
 	}
 
 	// Run checkRegistry with `registryCheckItemNotInstalled` as an `uninstall`
 	// We expect no action needed; Only error if action needed is true
-	actionNeeded, _ = checkRegistry(registryCheckItemNotInstalled, "uninstall")
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", actionNeeded)
+	result, _ = checkRegistry(registryCheckItemNotInstalled, "uninstall")
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", result.ActionNeeded)
 	}
 
 	// update
 
 	// Run checkRegistry with `registryCheckItem` as an `update`
 	// We expect no action needed; Only error if action needed is true
-	actionNeeded, _ = checkRegistry(registryCheckItem, "update")
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", actionNeeded)
+	result, _ = checkRegistry(registryCheckItem, "update")
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", result.ActionNeeded)
 	}
 
 	// Run checkRegistry with `registryCheckItemNotInstalled` as an `update`
 	// We expect no action needed; Only error if action needed is true
-	actionNeeded, _ = checkRegistry(registryCheckItemNotInstalled, "update")
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", actionNeeded)
+	result, _ = checkRegistry(registryCheckItemNotInstalled, "update")
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return false", result.ActionNeeded)
 	}
 
 	// Run checkRegistry with `registryCheckItemOutdated` as an `update`
 	// We expect action is needed; Only error if action needed is false
-	actionNeeded, _ = checkRegistry(registryCheckItemOutdated, "update")
-	if !actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", actionNeeded)
+	result, _ = checkRegistry(registryCheckItemOutdated, "update")
+	if !result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkRegistry to return true", result.ActionNeeded)
 	}
 
 }
@@ -256,49 +256,49 @@ func TestCheckScript(t *testing.T) {
 
 	// Set cachepath and run checkScript for scriptActionNoError
 	cachepath := fmt.Sprintf("testdata/%s/", statusActionNoError)
-	actionNeeded, err := checkScript(scriptActionNoError, cachepath, "install")
-	if !actionNeeded || err != nil {
-		fmt.Printf("action: %v; error: %v\n", actionNeeded, err)
+	result, err := checkScript(scriptActionNoError, cachepath, "install")
+	if !result.ActionNeeded || err != nil {
+		fmt.Printf("action: %v; error: %v\n", result.ActionNeeded, err)
 		t.Errorf("Expected checkScript to action and no error")
 	}
 
 	// Set cachepath and run checkScript for scriptNoActionNoError
 	cachepath = fmt.Sprintf("testdata/%s/", statusActionNoError)
-	actionNeeded, err = checkScript(scriptActionNoError, cachepath, "uninstall")
-	if actionNeeded || err != nil {
-		fmt.Printf("action: %v; error: %v\n", actionNeeded, err)
+	result, err = checkScript(scriptActionNoError, cachepath, "uninstall")
+	if result.ActionNeeded || err != nil {
+		fmt.Printf("action: %v; error: %v\n", result.ActionNeeded, err)
 		t.Errorf("Expected checkScript to no action and no error")
 	}
 
 	// Set cachepath and run checkScript for scriptNoActionNoError
 	cachepath = fmt.Sprintf("testdata/%s/", statusNoActionNoError)
-	actionNeeded, err = checkScript(scriptNoActionNoError, cachepath, "install")
-	if actionNeeded || err != nil {
-		fmt.Printf("action: %v; error: %v\n", actionNeeded, err)
+	result, err = checkScript(scriptNoActionNoError, cachepath, "install")
+	if result.ActionNeeded || err != nil {
+		fmt.Printf("action: %v; error: %v\n", result.ActionNeeded, err)
 		t.Errorf("Expected checkScript to return no action and no error")
 	}
 
 	// Set cachepath and run checkScript for scriptActionNoError
 	cachepath = fmt.Sprintf("testdata/%s/", statusNoActionNoError)
-	actionNeeded, err = checkScript(scriptNoActionNoError, cachepath, "uninstall")
-	if !actionNeeded || err != nil {
-		fmt.Printf("action: %v; error: %v\n", actionNeeded, err)
+	result, err = checkScript(scriptNoActionNoError, cachepath, "uninstall")
+	if !result.ActionNeeded || err != nil {
+		fmt.Printf("action: %v; error: %v\n", result.ActionNeeded, err)
 		t.Errorf("Expected checkScript to action and no error")
 	}
 
 	// Set cachepath and run checkScript for scriptActionNoError as update
 	cachepath = fmt.Sprintf("testdata/%s/", statusActionNoError)
-	actionNeeded, err = checkScript(scriptActionNoError, cachepath, "update")
-	if !actionNeeded || err != nil {
-		fmt.Printf("action: %v; error: %v\n", actionNeeded, err)
+	result, err = checkScript(scriptActionNoError, cachepath, "update")
+	if !result.ActionNeeded || err != nil {
+		fmt.Printf("action: %v; error: %v\n", result.ActionNeeded, err)
 		t.Errorf("Expected checkScript update to action and no error")
 	}
 
 	// Set cachepath and run checkScript for scriptNoActionNoError as update
 	cachepath = fmt.Sprintf("testdata/%s/", statusNoActionNoError)
-	actionNeeded, err = checkScript(scriptNoActionNoError, cachepath, "update")
-	if actionNeeded || err != nil {
-		fmt.Printf("action: %v; error: %v\n", actionNeeded, err)
+	result, err = checkScript(scriptNoActionNoError, cachepath, "update")
+	if result.ActionNeeded || err != nil {
+		fmt.Printf("action: %v; error: %v\n", result.ActionNeeded, err)
 		t.Errorf("Expected checkScript update to no action and no error")
 	}
 }
@@ -308,52 +308,52 @@ func TestCheckPath(t *testing.T) {
 
 	// Run checkPath for pathInstalled
 	// We expect action is not needed; Only error if action needed is true
-	actionNeeded, err := checkPath(pathInstalled, "install")
+	result, err := checkPath(pathInstalled, "install")
 	if err != nil {
 		t.Errorf("checkPath failed: %v", err)
 	}
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkPath to return false", actionNeeded)
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkPath to return false", result.ActionNeeded)
 	}
 
 	// Run checkPath for file that doesn't exist
 	// We expect action is not needed; Only error if action needed is true
-	actionNeeded, err = checkPath(pathMissing, "update")
+	result, err = checkPath(pathMissing, "update")
 	if err != nil {
 		t.Errorf("checkPath failed: %v", err)
 	}
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkPath to return false", actionNeeded)
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkPath to return false", result.ActionNeeded)
 	}
 
 	// Run checkPath for pathNotInstalled
-	// We expect action is needed; Only error if actionNeeded is false
-	actionNeeded, err = checkPath(pathNotInstalled, "install")
+	// We expect action is needed; Only error if result.ActionNeeded is false
+	result, err = checkPath(pathNotInstalled, "install")
 	if err != nil {
 		t.Error(err)
 	}
-	if !actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkPath to return true", actionNeeded)
+	if !result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkPath to return true", result.ActionNeeded)
 	}
 
 	// Run checkPath for pathMetadataInstalled
-	// We expect action is not needed; Only error if actionNeeded is true
-	actionNeeded, err = checkPath(pathMetadataInstalled, "install")
+	// We expect action is not needed; Only error if result.ActionNeeded is true
+	result, err = checkPath(pathMetadataInstalled, "install")
 	if err != nil {
 		t.Error(err)
 	}
-	if actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkPath to return false", actionNeeded)
+	if result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkPath to return false", result.ActionNeeded)
 	}
 
 	// Run checkPath for pathMetadataOutdated
-	// We expect action is needed; Only error if actionNeeded is false
-	actionNeeded, err = checkPath(pathMetadataOutdated, "install")
+	// We expect action is needed; Only error if result.ActionNeeded is false
+	result, err = checkPath(pathMetadataOutdated, "install")
 	if err != nil {
 		t.Error(err)
 	}
-	if !actionNeeded {
-		t.Errorf("actionNeeded: %v; Expected checkPath to return true", actionNeeded)
+	if !result.ActionNeeded {
+		t.Errorf("actionNeeded: %v; Expected checkPath to return true", result.ActionNeeded)
 	}
 
 }